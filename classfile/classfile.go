@@ -0,0 +1,249 @@
+package classfile
+
+import (
+	"archive/zip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	magic        = 0xCAFEBABE
+	AccInterface = 0x0200
+)
+
+type Attribute struct {
+	Name string
+	Data []byte
+}
+
+type Member struct {
+	AccessFlags uint16
+	Name        string
+	Descriptor  string
+	Attributes  []Attribute
+}
+
+type ClassFile struct {
+	MinorVersion uint16
+	MajorVersion uint16
+	ConstantPool ConstantPool
+	AccessFlags  uint16
+	ThisClass    string   // 内部名称，如 net/minecraft/Foo
+	SuperClass   string   // java/lang/Object 和 module-info 之外均不为空
+	Interfaces   []string // 内部名称
+	Fields       []Member
+	Methods      []Member
+	Attributes   []Attribute
+}
+
+func Parse(data []byte) (*ClassFile, error) {
+	r := &reader{data: data}
+	if r.u4() != magic {
+		if r.err != nil {
+			return nil, r.err
+		}
+		return nil, errors.New("not a class file")
+	}
+	cf := &ClassFile{}
+	cf.MinorVersion, cf.MajorVersion = r.u2(), r.u2()
+	pool, err := parseConstantPool(r)
+	if err != nil {
+		return nil, err
+	}
+	cf.ConstantPool = pool
+	cf.AccessFlags = r.u2()
+	cf.ThisClass = pool.ClassName(r.u2())
+	cf.SuperClass = pool.ClassName(r.u2())
+	interfaceCount := int(r.u2())
+	for i := 0; i < interfaceCount && r.err == nil; i++ {
+		cf.Interfaces = append(cf.Interfaces, pool.ClassName(r.u2()))
+	}
+	cf.Fields = parseMembers(r, pool)
+	cf.Methods = parseMembers(r, pool)
+	cf.Attributes = parseAttributes(r, pool)
+	if r.err != nil {
+		return nil, r.err
+	}
+	return cf, nil
+}
+
+func parseMembers(r *reader, pool ConstantPool) []Member {
+	count := int(r.u2())
+	members := make([]Member, 0, count)
+	for i := 0; i < count && r.err == nil; i++ {
+		members = append(members, Member{
+			AccessFlags: r.u2(),
+			Name:        pool.Utf8(r.u2()),
+			Descriptor:  pool.Utf8(r.u2()),
+			Attributes:  parseAttributes(r, pool),
+		})
+	}
+	return members
+}
+
+func parseAttributes(r *reader, pool ConstantPool) []Attribute {
+	count := int(r.u2())
+	attributes := make([]Attribute, 0, count)
+	for i := 0; i < count && r.err == nil; i++ {
+		name := pool.Utf8(r.u2())
+		attributes = append(attributes, Attribute{Name: name, Data: r.bytes(int(r.u4()))})
+	}
+	return attributes
+}
+
+// FindAttribute 按名称查找属性，找不到时返回 nil
+func FindAttribute(attributes []Attribute, name string) *Attribute {
+	for i := range attributes {
+		if attributes[i].Name == name {
+			return &attributes[i]
+		}
+	}
+	return nil
+}
+
+// InnerClass InnerClasses 属性中的一项，下标均为常量池下标，匿名类的 OuterClass 和 Name 为 0
+type InnerClass struct {
+	InnerClass  uint16 // CONSTANT_Class
+	OuterClass  uint16 // CONSTANT_Class
+	Name        uint16 // CONSTANT_Utf8，源码中的简单名
+	AccessFlags uint16
+}
+
+// EnclosingMethod 局部类和匿名类所在的类和方法，不在方法中时 Method 为 0
+type EnclosingMethod struct {
+	Class  uint16 // CONSTANT_Class
+	Method uint16 // CONSTANT_NameAndType
+}
+
+// InnerClasses 解析类的 InnerClasses 属性
+func (cf *ClassFile) InnerClasses() []InnerClass {
+	attribute := FindAttribute(cf.Attributes, "InnerClasses")
+	if attribute == nil {
+		return nil
+	}
+	r := &reader{data: attribute.Data}
+	count := int(r.u2())
+	result := make([]InnerClass, 0, count)
+	for i := 0; i < count; i++ {
+		inner := InnerClass{InnerClass: r.u2(), OuterClass: r.u2(), Name: r.u2(), AccessFlags: r.u2()}
+		if r.err != nil {
+			break
+		}
+		result = append(result, inner)
+	}
+	return result
+}
+
+// EnclosingMethod 解析类的 EnclosingMethod 属性，没有时返回 nil
+func (cf *ClassFile) EnclosingMethod() *EnclosingMethod {
+	attribute := FindAttribute(cf.Attributes, "EnclosingMethod")
+	if attribute == nil || len(attribute.Data) < 4 {
+		return nil
+	}
+	return &EnclosingMethod{
+		Class:  binary.BigEndian.Uint16(attribute.Data),
+		Method: binary.BigEndian.Uint16(attribute.Data[2:]),
+	}
+}
+
+// NestHost 返回 NestHost 属性中嵌套宿主类的常量池下标，没有时返回 0
+func (cf *ClassFile) NestHost() uint16 {
+	if attribute := FindAttribute(cf.Attributes, "NestHost"); attribute != nil && len(attribute.Data) >= 2 {
+		return binary.BigEndian.Uint16(attribute.Data)
+	}
+	return 0
+}
+
+// NestMembers 返回 NestMembers 属性中嵌套成员类的常量池下标
+func (cf *ClassFile) NestMembers() []uint16 {
+	attribute := FindAttribute(cf.Attributes, "NestMembers")
+	if attribute == nil {
+		return nil
+	}
+	r := &reader{data: attribute.Data}
+	count := int(r.u2())
+	result := make([]uint16, 0, count)
+	for i := 0; i < count; i++ {
+		index := r.u2()
+		if r.err != nil {
+			break
+		}
+		result = append(result, index)
+	}
+	return result
+}
+
+// IsClassEntry 判断 jar 条目是否为普通类文件，排除多版本目录
+func IsClassEntry(name string) bool {
+	return strings.HasSuffix(name, ".class") && !strings.HasPrefix(name, "META-INF/")
+}
+
+// ReadEntry 从已打开的 jar 中解析单个类文件
+func ReadEntry(file *zip.File) (*ClassFile, error) {
+	rc, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		return nil, err
+	}
+	cf, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", file.Name, err)
+	}
+	return cf, nil
+}
+
+// WalkJar 依次解析 jar 中的所有类文件
+func WalkJar(path string, fn func(cf *ClassFile) error) error {
+	jar, err := zip.OpenReader(path)
+	if err != nil {
+		return err
+	}
+	defer jar.Close()
+	for _, file := range jar.File {
+		if !IsClassEntry(file.Name) {
+			continue
+		}
+		cf, err := ReadEntry(file)
+		if err != nil {
+			return err
+		}
+		if err := fn(cf); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var ErrClassNotFound = errors.New("class not found in jar")
+
+// ReadClass 从 jar 中读取一个类，name 可以是内部名称或点分名称，
+// 点分的内部类（如 a.b.Foo.Bar）会依次尝试将末尾的点替换为 $
+func ReadClass(path, name string) (*ClassFile, error) {
+	jar, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, err
+	}
+	defer jar.Close()
+	entries := make(map[string]*zip.File, len(jar.File))
+	for _, file := range jar.File {
+		entries[file.Name] = file
+	}
+	candidate := strings.ReplaceAll(strings.TrimSuffix(name, ".class"), ".", "/")
+	for {
+		if file, ok := entries[candidate+".class"]; ok {
+			return ReadEntry(file)
+		}
+		idx := strings.LastIndexByte(candidate, '/')
+		if idx < 0 {
+			return nil, ErrClassNotFound
+		}
+		candidate = candidate[:idx] + "$" + candidate[idx+1:]
+	}
+}
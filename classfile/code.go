@@ -0,0 +1,198 @@
+package classfile
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sort"
+)
+
+type ExceptionHandler struct {
+	StartPc   int
+	EndPc     int
+	HandlerPc int
+	CatchType string // 内部名称，为空表示 finally
+}
+
+type Code struct {
+	MaxStack   int
+	MaxLocals  int
+	Bytecode   []byte
+	Handlers   []ExceptionHandler
+	Attributes []Attribute
+}
+
+type Switch struct {
+	Default int     // 绝对偏移
+	Keys    []int32 // tableswitch 为 low..high
+	Targets []int   // 绝对偏移，与 Keys 一一对应
+}
+
+type Instruction struct {
+	Offset int
+	Opcode uint8
+	Wide   bool
+	Index  int     // 常量池下标或局部变量下标
+	Value  int     // 立即数、iinc 增量、invokeinterface 参数个数、multianewarray 维度或 newarray 类型
+	Target int     // 跳转的绝对偏移
+	Switch *Switch // 仅 tableswitch 和 lookupswitch
+}
+
+type LineNumber struct {
+	StartPc int
+	Line    int
+}
+
+type BootstrapMethod struct {
+	MethodRef uint16   // CONSTANT_MethodHandle 下标
+	Arguments []uint16 // 常量池下标
+}
+
+// ParseCode 解析方法的 Code 属性，没有方法体时返回 nil
+func (m *Member) ParseCode(pool ConstantPool) (*Code, error) {
+	attribute := FindAttribute(m.Attributes, "Code")
+	if attribute == nil {
+		return nil, nil
+	}
+	r := &reader{data: attribute.Data}
+	code := &Code{MaxStack: int(r.u2()), MaxLocals: int(r.u2())}
+	code.Bytecode = r.bytes(int(r.u4()))
+	handlerCount := int(r.u2())
+	for i := 0; i < handlerCount && r.err == nil; i++ {
+		code.Handlers = append(code.Handlers, ExceptionHandler{
+			StartPc:   int(r.u2()),
+			EndPc:     int(r.u2()),
+			HandlerPc: int(r.u2()),
+			CatchType: pool.ClassName(r.u2()),
+		})
+	}
+	code.Attributes = parseAttributes(r, pool)
+	if r.err != nil {
+		return nil, fmt.Errorf("%s%s: %w", m.Name, m.Descriptor, r.err)
+	}
+	return code, nil
+}
+
+// Instructions 逐条解码字节码
+func (c *Code) Instructions() ([]Instruction, error) {
+	code := c.Bytecode
+	var result []Instruction
+	for pc := 0; pc < len(code); {
+		ins := Instruction{Offset: pc, Opcode: code[pc]}
+		r := &reader{data: code, pos: pc + 1}
+		switch opcodes[ins.Opcode].operand {
+		case operandByte:
+			ins.Value = int(int8(r.u1()))
+		case operandShort:
+			ins.Value = int(int16(r.u2()))
+		case operandLocal, operandConst1:
+			ins.Index = int(r.u1())
+		case operandConst2:
+			ins.Index = int(r.u2())
+		case operandBranch2:
+			ins.Target = pc + int(int16(r.u2()))
+		case operandBranch4:
+			ins.Target = pc + int(int32(r.u4()))
+		case operandIinc:
+			ins.Index, ins.Value = int(r.u1()), int(int8(r.u1()))
+		case operandInvokeI:
+			ins.Index, ins.Value = int(r.u2()), int(r.u1())
+			r.u1()
+		case operandInvokeD:
+			ins.Index = int(r.u2())
+			r.u2()
+		case operandMultiNew:
+			ins.Index, ins.Value = int(r.u2()), int(r.u1())
+		case operandSwitch:
+			// 操作数按 4 字节对齐
+			r.pos = (pc + 4) &^ 3
+			ins.Switch = &Switch{Default: pc + int(int32(r.u4()))}
+			if ins.Opcode == OpTableSwitch {
+				low, high := int32(r.u4()), int32(r.u4())
+				if r.err == nil && (high < low || int(high-low) >= len(code)) {
+					return nil, fmt.Errorf("invalid tableswitch at %d", pc)
+				}
+				for key := low; r.err == nil && key <= high; key++ {
+					ins.Switch.Keys = append(ins.Switch.Keys, key)
+					ins.Switch.Targets = append(ins.Switch.Targets, pc+int(int32(r.u4())))
+					if key == high {
+						break
+					}
+				}
+			} else {
+				count := int(int32(r.u4()))
+				if r.err == nil && (count < 0 || count > len(code)) {
+					return nil, fmt.Errorf("invalid lookupswitch at %d", pc)
+				}
+				for i := 0; i < count && r.err == nil; i++ {
+					ins.Switch.Keys = append(ins.Switch.Keys, int32(r.u4()))
+					ins.Switch.Targets = append(ins.Switch.Targets, pc+int(int32(r.u4())))
+				}
+			}
+		case operandWide:
+			ins.Wide = true
+			ins.Opcode = r.u1()
+			ins.Index = int(r.u2())
+			if ins.Opcode == OpIinc {
+				ins.Value = int(int16(r.u2()))
+			}
+		case operandInvalid:
+			return nil, fmt.Errorf("invalid opcode 0x%02x at %d", ins.Opcode, pc)
+		}
+		if r.err != nil {
+			return nil, fmt.Errorf("truncated instruction at %d", pc)
+		}
+		result = append(result, ins)
+		pc = r.pos
+	}
+	return result, nil
+}
+
+// LineNumbers 返回按 StartPc 排序的行号表
+func (c *Code) LineNumbers() []LineNumber {
+	var result []LineNumber
+	for _, attribute := range c.Attributes {
+		if attribute.Name != "LineNumberTable" || len(attribute.Data) < 2 {
+			continue
+		}
+		data := attribute.Data
+		count := int(binary.BigEndian.Uint16(data))
+		for i := 0; i < count && 2+i*4+4 <= len(data); i++ {
+			entry := data[2+i*4:]
+			result = append(result, LineNumber{
+				StartPc: int(binary.BigEndian.Uint16(entry)),
+				Line:    int(binary.BigEndian.Uint16(entry[2:])),
+			})
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].StartPc < result[j].StartPc })
+	return result
+}
+
+// LineAt 返回字节码偏移对应的源码行号，未知时返回 0
+func LineAt(lines []LineNumber, pc int) int {
+	i := sort.Search(len(lines), func(i int) bool { return lines[i].StartPc > pc })
+	if i == 0 {
+		return 0
+	}
+	return lines[i-1].Line
+}
+
+// BootstrapMethods 解析类的 BootstrapMethods 属性
+func (cf *ClassFile) BootstrapMethods() []BootstrapMethod {
+	attribute := FindAttribute(cf.Attributes, "BootstrapMethods")
+	if attribute == nil {
+		return nil
+	}
+	r := &reader{data: attribute.Data}
+	count := int(r.u2())
+	result := make([]BootstrapMethod, 0, count)
+	for i := 0; i < count && r.err == nil; i++ {
+		method := BootstrapMethod{MethodRef: r.u2()}
+		argCount := int(r.u2())
+		for j := 0; j < argCount && r.err == nil; j++ {
+			method.Arguments = append(method.Arguments, r.u2())
+		}
+		result = append(result, method)
+	}
+	return result
+}
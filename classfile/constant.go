@@ -0,0 +1,166 @@
+package classfile
+
+import (
+	"fmt"
+	"math"
+	"unicode/utf16"
+)
+
+const (
+	TagUtf8               = 1
+	TagInteger            = 3
+	TagFloat              = 4
+	TagLong               = 5
+	TagDouble             = 6
+	TagClass              = 7
+	TagString             = 8
+	TagFieldref           = 9
+	TagMethodref          = 10
+	TagInterfaceMethodref = 11
+	TagNameAndType        = 12
+	TagMethodHandle       = 15
+	TagMethodType         = 16
+	TagDynamic            = 17
+	TagInvokeDynamic      = 18
+	TagModule             = 19
+	TagPackage            = 20
+)
+
+// Constant 常量池中的一项，Ref1/Ref2 的含义取决于 Tag
+type Constant struct {
+	Tag     uint8
+	Utf8    string
+	Int     int32
+	Long    int64
+	Float   float32
+	Double  float64
+	Ref1    uint16
+	Ref2    uint16
+	RefKind uint8
+}
+
+// ConstantPool 下标从 1 开始，Long 和 Double 占用两个槽位
+type ConstantPool []Constant
+
+func parseConstantPool(r *reader) (ConstantPool, error) {
+	count := int(r.u2())
+	pool := make(ConstantPool, count)
+	for i := 1; i < count; i++ {
+		c := Constant{Tag: r.u1()}
+		switch c.Tag {
+		case TagUtf8:
+			c.Utf8 = decodeModifiedUtf8(r.bytes(int(r.u2())))
+		case TagInteger:
+			c.Int = int32(r.u4())
+		case TagFloat:
+			c.Float = math.Float32frombits(r.u4())
+		case TagLong:
+			c.Long = int64(r.u8())
+		case TagDouble:
+			c.Double = math.Float64frombits(r.u8())
+		case TagClass, TagString, TagMethodType, TagModule, TagPackage:
+			c.Ref1 = r.u2()
+		case TagFieldref, TagMethodref, TagInterfaceMethodref, TagNameAndType, TagDynamic, TagInvokeDynamic:
+			c.Ref1, c.Ref2 = r.u2(), r.u2()
+		case TagMethodHandle:
+			c.RefKind, c.Ref1 = r.u1(), r.u2()
+		default:
+			if r.err != nil {
+				return nil, r.err
+			}
+			return nil, fmt.Errorf("unknown constant pool tag %d at index %d", c.Tag, i)
+		}
+		pool[i] = c
+		if c.Tag == TagLong || c.Tag == TagDouble {
+			i++
+		}
+	}
+	return pool, r.err
+}
+
+func (cp ConstantPool) get(index uint16) *Constant {
+	if index == 0 || int(index) >= len(cp) {
+		return nil
+	}
+	return &cp[index]
+}
+
+// Utf8 返回指定下标的字符串常量，下标无效时返回空串
+func (cp ConstantPool) Utf8(index uint16) string {
+	if c := cp.get(index); c != nil && c.Tag == TagUtf8 {
+		return c.Utf8
+	}
+	return ""
+}
+
+// ClassName 返回 CONSTANT_Class 的内部名称，如 net/minecraft/Foo
+func (cp ConstantPool) ClassName(index uint16) string {
+	if c := cp.get(index); c != nil && c.Tag == TagClass {
+		return cp.Utf8(c.Ref1)
+	}
+	return ""
+}
+
+// NameAndType 返回 CONSTANT_NameAndType 的名称和描述符
+func (cp ConstantPool) NameAndType(index uint16) (string, string) {
+	if c := cp.get(index); c != nil && c.Tag == TagNameAndType {
+		return cp.Utf8(c.Ref1), cp.Utf8(c.Ref2)
+	}
+	return "", ""
+}
+
+// MemberRef 返回字段或方法引用的所属类、名称和描述符
+func (cp ConstantPool) MemberRef(index uint16) (owner, name, descriptor string) {
+	c := cp.get(index)
+	if c == nil || (c.Tag != TagFieldref && c.Tag != TagMethodref && c.Tag != TagInterfaceMethodref) {
+		return "", "", ""
+	}
+	name, descriptor = cp.NameAndType(c.Ref2)
+	return cp.ClassName(c.Ref1), name, descriptor
+}
+
+// Tag 返回指定下标常量的类型，下标无效时返回 0
+func (cp ConstantPool) Tag(index uint16) uint8 {
+	if c := cp.get(index); c != nil {
+		return c.Tag
+	}
+	return 0
+}
+
+// Get 返回指定下标的常量，下标无效时返回 nil
+func (cp ConstantPool) Get(index uint16) *Constant {
+	return cp.get(index)
+}
+
+// 类文件使用 Modified UTF-8：\0 编码为两字节，增补字符编码为代理对
+func decodeModifiedUtf8(b []byte) string {
+	ascii := true
+	for _, c := range b {
+		if c >= 0x80 || c == 0 {
+			ascii = false
+			break
+		}
+	}
+	if ascii {
+		return string(b)
+	}
+	units := make([]uint16, 0, len(b))
+	for i := 0; i < len(b); {
+		c := b[i]
+		switch {
+		case c < 0x80:
+			units = append(units, uint16(c))
+			i++
+		case c&0xE0 == 0xC0 && i+1 < len(b):
+			units = append(units, uint16(c&0x1F)<<6|uint16(b[i+1]&0x3F))
+			i += 2
+		case c&0xF0 == 0xE0 && i+2 < len(b):
+			units = append(units, uint16(c&0x0F)<<12|uint16(b[i+1]&0x3F)<<6|uint16(b[i+2]&0x3F))
+			i += 3
+		default:
+			units = append(units, 0xFFFD)
+			i++
+		}
+	}
+	return string(utf16.Decode(units))
+}
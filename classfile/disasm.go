@@ -0,0 +1,531 @@
+package classfile
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+var ErrMemberNotFound = errors.New("member not found in class")
+
+type accessFlag struct {
+	mask    uint16
+	name    string
+	keyword string // 声明中使用的修饰符，为空表示不出现在声明中
+}
+
+var (
+	classFlags = []accessFlag{
+		{0x0001, "ACC_PUBLIC", "public"}, {0x0010, "ACC_FINAL", "final"}, {0x0020, "ACC_SUPER", ""},
+		{0x0200, "ACC_INTERFACE", ""}, {0x0400, "ACC_ABSTRACT", "abstract"}, {0x1000, "ACC_SYNTHETIC", ""},
+		{0x2000, "ACC_ANNOTATION", ""}, {0x4000, "ACC_ENUM", ""}, {0x8000, "ACC_MODULE", ""},
+	}
+	fieldFlags = []accessFlag{
+		{0x0001, "ACC_PUBLIC", "public"}, {0x0002, "ACC_PRIVATE", "private"}, {0x0004, "ACC_PROTECTED", "protected"},
+		{0x0008, "ACC_STATIC", "static"}, {0x0010, "ACC_FINAL", "final"}, {0x0040, "ACC_VOLATILE", "volatile"},
+		{0x0080, "ACC_TRANSIENT", "transient"}, {0x1000, "ACC_SYNTHETIC", ""}, {0x4000, "ACC_ENUM", ""},
+	}
+	innerClassFlags = []accessFlag{
+		{0x0001, "ACC_PUBLIC", "public"}, {0x0002, "ACC_PRIVATE", "private"}, {0x0004, "ACC_PROTECTED", "protected"},
+		{0x0008, "ACC_STATIC", "static"}, {0x0010, "ACC_FINAL", "final"}, {0x0200, "ACC_INTERFACE", ""},
+		{0x0400, "ACC_ABSTRACT", "abstract"}, {0x1000, "ACC_SYNTHETIC", ""}, {0x2000, "ACC_ANNOTATION", ""},
+		{0x4000, "ACC_ENUM", ""},
+	}
+	methodFlags = []accessFlag{
+		{0x0001, "ACC_PUBLIC", "public"}, {0x0002, "ACC_PRIVATE", "private"}, {0x0004, "ACC_PROTECTED", "protected"},
+		{0x0008, "ACC_STATIC", "static"}, {0x0010, "ACC_FINAL", "final"}, {0x0020, "ACC_SYNCHRONIZED", "synchronized"},
+		{0x0040, "ACC_BRIDGE", ""}, {0x0080, "ACC_VARARGS", ""}, {0x0100, "ACC_NATIVE", "native"},
+		{0x0400, "ACC_ABSTRACT", "abstract"}, {0x0800, "ACC_STRICT", "strictfp"}, {0x1000, "ACC_SYNTHETIC", ""},
+	}
+	newArrayTypes = map[int]string{4: "boolean", 5: "char", 6: "float", 7: "double", 8: "byte", 9: "short", 10: "int", 11: "long"}
+	handleKinds   = []string{"", "REF_getField", "REF_getStatic", "REF_putField", "REF_putStatic", "REF_invokeVirtual",
+		"REF_invokeStatic", "REF_invokeSpecial", "REF_newInvokeSpecial", "REF_invokeInterface"}
+)
+
+const (
+	accPrivate    = 0x0002
+	accStatic     = 0x0008
+	accAbstract   = 0x0400
+	accAnnotation = 0x2000
+)
+
+// Disassemble 以类似 javap -c -p 的格式输出类，末尾附带 javap -v 中内部类和嵌套关系的属性，member 为 名称 或 名称加描述符（如 tick()V）时只输出匹配的成员
+func Disassemble(w io.Writer, cf *ClassFile, member string) error {
+	out := bufio.NewWriter(w)
+	d := &disassembler{out: out, cf: cf}
+	var fields, methods []*Member
+	for i := range cf.Fields {
+		if matchMember(&cf.Fields[i], member) {
+			fields = append(fields, &cf.Fields[i])
+		}
+	}
+	for i := range cf.Methods {
+		if matchMember(&cf.Methods[i], member) {
+			methods = append(methods, &cf.Methods[i])
+		}
+	}
+	if member != "" && len(fields) == 0 && len(methods) == 0 {
+		return ErrMemberNotFound
+	}
+	d.header()
+	out.WriteString("{\n")
+	first := true
+	for _, field := range fields {
+		if !first {
+			out.WriteString("\n")
+		}
+		first = false
+		d.field(field)
+	}
+	for _, method := range methods {
+		if !first {
+			out.WriteString("\n")
+		}
+		first = false
+		if err := d.method(method); err != nil {
+			return err
+		}
+	}
+	out.WriteString("}\n")
+	if source := d.sourceFile(); source != "" {
+		fmt.Fprintf(out, "SourceFile: %q\n", source)
+	}
+	d.classAttributes()
+	return out.Flush()
+}
+
+func matchMember(m *Member, member string) bool {
+	if member == "" {
+		return true
+	}
+	if strings.Contains(member, "(") {
+		return m.Name+m.Descriptor == member
+	}
+	return m.Name == member
+}
+
+type disassembler struct {
+	out *bufio.Writer
+	cf  *ClassFile
+}
+
+func (d *disassembler) sourceFile() string {
+	if attribute := FindAttribute(d.cf.Attributes, "SourceFile"); attribute != nil && len(attribute.Data) >= 2 {
+		return d.cf.ConstantPool.Utf8(binary.BigEndian.Uint16(attribute.Data))
+	}
+	return ""
+}
+
+func (d *disassembler) signature(attributes []Attribute) string {
+	if attribute := FindAttribute(attributes, "Signature"); attribute != nil && len(attribute.Data) >= 2 {
+		return d.cf.ConstantPool.Utf8(binary.BigEndian.Uint16(attribute.Data))
+	}
+	return ""
+}
+
+// 与 javap -v 相同，按类文件中的顺序输出嵌套关系相关的属性
+func (d *disassembler) classAttributes() {
+	cf, pool := d.cf, d.cf.ConstantPool
+	for _, attribute := range cf.Attributes {
+		switch attribute.Name {
+		case "EnclosingMethod":
+			enclosing := cf.EnclosingMethod()
+			if enclosing == nil {
+				continue
+			}
+			comment := javaName(pool.ClassName(enclosing.Class))
+			if enclosing.Method != 0 {
+				name, _ := pool.NameAndType(enclosing.Method)
+				comment += "." + name
+			}
+			d.commented(fmt.Sprintf("EnclosingMethod: #%d.#%d", enclosing.Class, enclosing.Method), comment)
+		case "NestHost":
+			if host := cf.NestHost(); host != 0 {
+				fmt.Fprintf(d.out, "NestHost: class %s\n", pool.ClassName(host))
+			}
+		case "NestMembers":
+			fmt.Fprintf(d.out, "NestMembers:\n")
+			for _, member := range cf.NestMembers() {
+				fmt.Fprintf(d.out, "  %s\n", pool.ClassName(member))
+			}
+		case "InnerClasses":
+			fmt.Fprintf(d.out, "InnerClasses:\n")
+			for _, inner := range cf.InnerClasses() {
+				d.innerClass(inner)
+			}
+		}
+	}
+}
+
+// 格式为 修饰符 #名称= #内部类 of #外部类;  // 名称=class 内部类 of class 外部类
+func (d *disassembler) innerClass(inner InnerClass) {
+	pool := d.cf.ConstantPool
+	var entry, comment strings.Builder
+	entry.WriteString("  ")
+	// 接口的 abstract 是隐含的
+	flags := inner.AccessFlags
+	if flags&AccInterface != 0 {
+		flags &^= accAbstract
+	}
+	for _, keyword := range modifiers(flags, innerClassFlags) {
+		entry.WriteString(keyword + " ")
+	}
+	if inner.Name != 0 {
+		fmt.Fprintf(&entry, "#%d= ", inner.Name)
+		comment.WriteString(pool.Utf8(inner.Name) + "=")
+	}
+	fmt.Fprintf(&entry, "#%d", inner.InnerClass)
+	comment.WriteString("class " + pool.ClassName(inner.InnerClass))
+	if inner.OuterClass != 0 {
+		fmt.Fprintf(&entry, " of #%d", inner.OuterClass)
+		comment.WriteString(" of class " + pool.ClassName(inner.OuterClass))
+	}
+	entry.WriteString(";")
+	d.commented(entry.String(), comment.String())
+}
+
+func (d *disassembler) commented(line, comment string) {
+	fmt.Fprintf(d.out, "%-40s // %s\n", line, comment)
+}
+
+func (d *disassembler) header() {
+	cf := d.cf
+	if source := d.sourceFile(); source != "" {
+		fmt.Fprintf(d.out, "Compiled from %q\n", source)
+	}
+	var declaration []string
+	declaration = append(declaration, modifiers(cf.AccessFlags&^AccInterface, classFlags)...)
+	interfaces := make([]string, len(cf.Interfaces))
+	for i, name := range cf.Interfaces {
+		interfaces[i] = javaName(name)
+	}
+	if cf.AccessFlags&AccInterface != 0 {
+		declaration = removeString(declaration, "abstract")
+		if cf.AccessFlags&accAnnotation != 0 {
+			declaration = append(declaration, "@interface", javaName(cf.ThisClass))
+		} else {
+			declaration = append(declaration, "interface", javaName(cf.ThisClass))
+		}
+		if len(interfaces) > 0 {
+			declaration = append(declaration, "extends", strings.Join(interfaces, ", "))
+		}
+	} else {
+		declaration = append(declaration, "class", javaName(cf.ThisClass))
+		if cf.SuperClass != "" && cf.SuperClass != "java/lang/Object" {
+			declaration = append(declaration, "extends", javaName(cf.SuperClass))
+		}
+		if len(interfaces) > 0 {
+			declaration = append(declaration, "implements", strings.Join(interfaces, ", "))
+		}
+	}
+	fmt.Fprintf(d.out, "%s\n", strings.Join(declaration, " "))
+	if signature := d.signature(cf.Attributes); signature != "" {
+		fmt.Fprintf(d.out, "  Signature: %s\n", signature)
+	}
+	fmt.Fprintf(d.out, "  minor version: %d\n  major version: %d\n", cf.MinorVersion, cf.MajorVersion)
+	fmt.Fprintf(d.out, "  flags: (0x%04x) %s\n", cf.AccessFlags, strings.Join(flagNames(cf.AccessFlags, classFlags), ", "))
+}
+
+func (d *disassembler) field(field *Member) {
+	declaration := append(modifiers(field.AccessFlags, fieldFlags), javaType(field.Descriptor), field.Name)
+	fmt.Fprintf(d.out, "  %s;\n", strings.Join(declaration, " "))
+	d.memberInfo(field, fieldFlags)
+}
+
+func (d *disassembler) memberInfo(member *Member, flags []accessFlag) {
+	fmt.Fprintf(d.out, "    descriptor: %s\n", member.Descriptor)
+	fmt.Fprintf(d.out, "    flags: (0x%04x) %s\n", member.AccessFlags, strings.Join(flagNames(member.AccessFlags, flags), ", "))
+	if signature := d.signature(member.Attributes); signature != "" {
+		fmt.Fprintf(d.out, "    Signature: %s\n", signature)
+	}
+}
+
+func (d *disassembler) method(method *Member) error {
+	params, ret := methodTypes(method.Descriptor)
+	declaration := modifiers(method.AccessFlags, methodFlags)
+	switch method.Name {
+	case "<clinit>":
+		declaration = []string{"static", "{}"}
+	case "<init>":
+		declaration = append(declaration, javaName(d.cf.ThisClass)+"("+strings.Join(params, ", ")+")")
+	default:
+		if d.cf.AccessFlags&AccInterface != 0 && method.AccessFlags&(accAbstract|accStatic|accPrivate) == 0 {
+			declaration = append(declaration, "default")
+		}
+		declaration = append(declaration, ret, method.Name+"("+strings.Join(params, ", ")+")")
+	}
+	if exceptions := d.exceptions(method); len(exceptions) > 0 {
+		declaration = append(declaration, "throws", strings.Join(exceptions, ", "))
+	}
+	fmt.Fprintf(d.out, "  %s;\n", strings.Join(declaration, " "))
+	d.memberInfo(method, methodFlags)
+
+	code, err := method.ParseCode(d.cf.ConstantPool)
+	if err != nil || code == nil {
+		return err
+	}
+	argsSize := 0
+	if method.AccessFlags&accStatic == 0 {
+		argsSize++
+	}
+	for _, p := range params {
+		if p == "long" || p == "double" {
+			argsSize += 2
+		} else {
+			argsSize++
+		}
+	}
+	fmt.Fprintf(d.out, "    Code:\n      stack=%d, locals=%d, args_size=%d\n", code.MaxStack, code.MaxLocals, argsSize)
+	instructions, err := code.Instructions()
+	if err != nil {
+		return err
+	}
+	for _, ins := range instructions {
+		d.instruction(ins)
+	}
+	if len(code.Handlers) > 0 {
+		fmt.Fprintf(d.out, "      Exception table:\n         from    to  target type\n")
+		for _, h := range code.Handlers {
+			catchType := "any"
+			if h.CatchType != "" {
+				catchType = "Class " + h.CatchType
+			}
+			fmt.Fprintf(d.out, "         %5d %5d %5d   %s\n", h.StartPc, h.EndPc, h.HandlerPc, catchType)
+		}
+	}
+	if lines := code.LineNumbers(); len(lines) > 0 {
+		fmt.Fprintf(d.out, "      LineNumberTable:\n")
+		for _, line := range lines {
+			fmt.Fprintf(d.out, "        line %d: %d\n", line.Line, line.StartPc)
+		}
+	}
+	return nil
+}
+
+func (d *disassembler) exceptions(method *Member) []string {
+	attribute := FindAttribute(method.Attributes, "Exceptions")
+	if attribute == nil {
+		return nil
+	}
+	r := &reader{data: attribute.Data}
+	count := int(r.u2())
+	var result []string
+	for i := 0; i < count && r.err == nil; i++ {
+		if name := d.cf.ConstantPool.ClassName(r.u2()); name != "" {
+			result = append(result, javaName(name))
+		}
+	}
+	return result
+}
+
+func (d *disassembler) instruction(ins Instruction) {
+	name := OpcodeName(ins.Opcode)
+	if ins.Wide {
+		name += "_w"
+	}
+	var operand, comment string
+	switch opcodes[ins.Opcode].operand {
+	case operandByte:
+		operand = strconv.Itoa(ins.Value)
+		if ins.Opcode == OpNewArray {
+			operand = newArrayTypes[ins.Value]
+		}
+	case operandShort:
+		operand = strconv.Itoa(ins.Value)
+	case operandLocal:
+		operand = strconv.Itoa(ins.Index)
+	case operandConst1, operandConst2:
+		operand = "#" + strconv.Itoa(ins.Index)
+		comment = d.constant(uint16(ins.Index))
+	case operandBranch2, operandBranch4:
+		operand = strconv.Itoa(ins.Target)
+	case operandIinc:
+		operand = strconv.Itoa(ins.Index) + ", " + strconv.Itoa(ins.Value)
+	case operandInvokeI, operandMultiNew:
+		operand = "#" + strconv.Itoa(ins.Index) + ",  " + strconv.Itoa(ins.Value)
+		comment = d.constant(uint16(ins.Index))
+	case operandInvokeD:
+		operand = "#" + strconv.Itoa(ins.Index) + ",  0"
+		comment = d.constant(uint16(ins.Index))
+	case operandSwitch:
+		d.switchInstruction(ins, name)
+		return
+	default:
+		if ins.Wide {
+			// wide 只会修饰局部变量指令和 iinc
+			operand = strconv.Itoa(ins.Index)
+			if ins.Opcode == OpIinc {
+				operand += ", " + strconv.Itoa(ins.Value)
+			}
+		}
+	}
+	line := fmt.Sprintf("%10d: %s", ins.Offset, name)
+	if operand != "" {
+		line = fmt.Sprintf("%10d: %-13s %s", ins.Offset, name, operand)
+	}
+	if comment != "" {
+		line = fmt.Sprintf("%-43s // %s", line, comment)
+	}
+	fmt.Fprintf(d.out, "%s\n", line)
+}
+
+func (d *disassembler) switchInstruction(ins Instruction, name string) {
+	s := ins.Switch
+	if ins.Opcode == OpTableSwitch && len(s.Keys) > 0 {
+		fmt.Fprintf(d.out, "%10d: %-13s { // %d to %d\n", ins.Offset, name, s.Keys[0], s.Keys[len(s.Keys)-1])
+	} else {
+		fmt.Fprintf(d.out, "%10d: %-13s { // %d\n", ins.Offset, name, len(s.Keys))
+	}
+	for i, key := range s.Keys {
+		fmt.Fprintf(d.out, "%24d: %d\n", key, s.Targets[i])
+	}
+	fmt.Fprintf(d.out, "%24s: %d\n            }\n", "default", s.Default)
+}
+
+// 常量的说明，与 javap 注释中的格式一致
+func (d *disassembler) constant(index uint16) string {
+	pool := d.cf.ConstantPool
+	c := pool.Get(index)
+	if c == nil {
+		return "invalid constant"
+	}
+	switch c.Tag {
+	case TagClass:
+		return "class " + pool.ClassName(index)
+	case TagString:
+		return "String " + escapeString(pool.Utf8(c.Ref1))
+	case TagInteger:
+		return "int " + strconv.Itoa(int(c.Int))
+	case TagFloat:
+		return "float " + strconv.FormatFloat(float64(c.Float), 'g', -1, 32) + "f"
+	case TagLong:
+		return "long " + strconv.FormatInt(c.Long, 10) + "l"
+	case TagDouble:
+		return "double " + strconv.FormatFloat(c.Double, 'g', -1, 64) + "d"
+	case TagFieldref:
+		return "Field " + d.memberRef(index)
+	case TagMethodref:
+		return "Method " + d.memberRef(index)
+	case TagInterfaceMethodref:
+		return "InterfaceMethod " + d.memberRef(index)
+	case TagMethodType:
+		return "MethodType " + pool.Utf8(c.Ref1)
+	case TagMethodHandle:
+		kind := ""
+		if int(c.RefKind) < len(handleKinds) {
+			kind = handleKinds[c.RefKind]
+		}
+		return "MethodHandle " + kind + " " + d.memberRef(c.Ref1)
+	case TagDynamic, TagInvokeDynamic:
+		name, descriptor := pool.NameAndType(c.Ref2)
+		prefix := "Dynamic"
+		if c.Tag == TagInvokeDynamic {
+			prefix = "InvokeDynamic"
+		}
+		return fmt.Sprintf("%s #%d:%s:%s", prefix, c.Ref1, name, descriptor)
+	}
+	return ""
+}
+
+// 所属类为当前类时省略类名
+func (d *disassembler) memberRef(index uint16) string {
+	owner, name, descriptor := d.cf.ConstantPool.MemberRef(index)
+	if name == "<init>" || name == "<clinit>" {
+		name = "\"" + name + "\""
+	}
+	if owner == d.cf.ThisClass {
+		return name + ":" + descriptor
+	}
+	return owner + "." + name + ":" + descriptor
+}
+
+func escapeString(s string) string {
+	quoted := strconv.Quote(s)
+	return quoted[1 : len(quoted)-1]
+}
+
+func modifiers(flags uint16, table []accessFlag) []string {
+	var result []string
+	for _, flag := range table {
+		if flags&flag.mask != 0 && flag.keyword != "" {
+			result = append(result, flag.keyword)
+		}
+	}
+	return result
+}
+
+func flagNames(flags uint16, table []accessFlag) []string {
+	var result []string
+	for _, flag := range table {
+		if flags&flag.mask != 0 {
+			result = append(result, flag.name)
+		}
+	}
+	return result
+}
+
+func removeString(slice []string, s string) []string {
+	result := slice[:0]
+	for _, item := range slice {
+		if item != s {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+// javaName 将内部名称转为 javap 使用的点分名称，内部类保留 $
+func javaName(internalName string) string {
+	return strings.ReplaceAll(internalName, "/", ".")
+}
+
+var primitiveTypes = map[byte]string{'Z': "boolean", 'B': "byte", 'C': "char", 'S': "short", 'I': "int",
+	'J': "long", 'F': "float", 'D': "double", 'V': "void"}
+
+// javaType 将字段描述符转为 Java 类型，如 [Ljava/lang/String; -> java.lang.String[]
+func javaType(descriptor string) string {
+	t, _ := parseType(descriptor)
+	return t
+}
+
+func parseType(descriptor string) (string, int) {
+	dims := 0
+	for dims < len(descriptor) && descriptor[dims] == '[' {
+		dims++
+	}
+	if dims >= len(descriptor) {
+		return descriptor, len(descriptor)
+	}
+	var name string
+	length := dims + 1
+	if descriptor[dims] == 'L' {
+		end := strings.IndexByte(descriptor[dims:], ';')
+		if end < 0 {
+			return descriptor, len(descriptor)
+		}
+		name = javaName(descriptor[dims+1 : dims+end])
+		length = dims + end + 1
+	} else {
+		name = primitiveTypes[descriptor[dims]]
+	}
+	return name + strings.Repeat("[]", dims), length
+}
+
+// methodTypes 返回方法描述符的参数类型和返回类型
+func methodTypes(descriptor string) ([]string, string) {
+	end := strings.IndexByte(descriptor, ')')
+	if !strings.HasPrefix(descriptor, "(") || end < 0 {
+		return nil, descriptor
+	}
+	var params []string
+	for rest := descriptor[1:end]; rest != ""; {
+		t, length := parseType(rest)
+		params = append(params, t)
+		rest = rest[length:]
+	}
+	return params, javaType(descriptor[end+1:])
+}
@@ -0,0 +1,109 @@
+package classfile
+
+const (
+	OpLdc             = 0x12
+	OpLdcW            = 0x13
+	OpLdc2W           = 0x14
+	OpIinc            = 0x84
+	OpTableSwitch     = 0xaa
+	OpLookupSwitch    = 0xab
+	OpGetStatic       = 0xb2
+	OpPutStatic       = 0xb3
+	OpGetField        = 0xb4
+	OpPutField        = 0xb5
+	OpInvokeVirtual   = 0xb6
+	OpInvokeSpecial   = 0xb7
+	OpInvokeStatic    = 0xb8
+	OpInvokeInterface = 0xb9
+	OpInvokeDynamic   = 0xba
+	OpNew             = 0xbb
+	OpNewArray        = 0xbc
+	OpANewArray       = 0xbd
+	OpCheckCast       = 0xc0
+	OpInstanceOf      = 0xc1
+	OpWide            = 0xc4
+	OpMultiANewArray  = 0xc5
+	OpGotoW           = 0xc8
+	OpJsrW            = 0xc9
+)
+
+// 操作数格式
+const (
+	operandNone     = iota
+	operandByte     // 有符号单字节立即数
+	operandShort    // 有符号双字节立即数
+	operandLocal    // 单字节局部变量下标
+	operandConst1   // 单字节常量池下标
+	operandConst2   // 双字节常量池下标
+	operandBranch2  // 双字节跳转偏移
+	operandBranch4  // 四字节跳转偏移
+	operandIinc     // 局部变量下标 + 有符号增量
+	operandInvokeI  // 常量池下标 + count + 0
+	operandInvokeD  // 常量池下标 + 0 + 0
+	operandMultiNew // 常量池下标 + 维度
+	operandSwitch   // tableswitch / lookupswitch
+	operandWide     // wide 前缀
+	operandInvalid
+)
+
+type opcodeInfo struct {
+	name    string
+	operand int
+}
+
+var opcodes [256]opcodeInfo
+
+func init() {
+	names := []string{"nop", "aconst_null", "iconst_m1", "iconst_0", "iconst_1", "iconst_2", "iconst_3", "iconst_4",
+		"iconst_5", "lconst_0", "lconst_1", "fconst_0", "fconst_1", "fconst_2", "dconst_0", "dconst_1", "bipush",
+		"sipush", "ldc", "ldc_w", "ldc2_w", "iload", "lload", "fload", "dload", "aload", "iload_0", "iload_1",
+		"iload_2", "iload_3", "lload_0", "lload_1", "lload_2", "lload_3", "fload_0", "fload_1", "fload_2", "fload_3",
+		"dload_0", "dload_1", "dload_2", "dload_3", "aload_0", "aload_1", "aload_2", "aload_3", "iaload", "laload",
+		"faload", "daload", "aaload", "baload", "caload", "saload", "istore", "lstore", "fstore", "dstore", "astore",
+		"istore_0", "istore_1", "istore_2", "istore_3", "lstore_0", "lstore_1", "lstore_2", "lstore_3", "fstore_0",
+		"fstore_1", "fstore_2", "fstore_3", "dstore_0", "dstore_1", "dstore_2", "dstore_3", "astore_0", "astore_1",
+		"astore_2", "astore_3", "iastore", "lastore", "fastore", "dastore", "aastore", "bastore", "castore", "sastore",
+		"pop", "pop2", "dup", "dup_x1", "dup_x2", "dup2", "dup2_x1", "dup2_x2", "swap", "iadd", "ladd", "fadd", "dadd",
+		"isub", "lsub", "fsub", "dsub", "imul", "lmul", "fmul", "dmul", "idiv", "ldiv", "fdiv", "ddiv", "irem", "lrem",
+		"frem", "drem", "ineg", "lneg", "fneg", "dneg", "ishl", "lshl", "ishr", "lshr", "iushr", "lushr", "iand", "land",
+		"ior", "lor", "ixor", "lxor", "iinc", "i2l", "i2f", "i2d", "l2i", "l2f", "l2d", "f2i", "f2l", "f2d", "d2i", "d2l",
+		"d2f", "i2b", "i2c", "i2s", "lcmp", "fcmpl", "fcmpg", "dcmpl", "dcmpg", "ifeq", "ifne", "iflt", "ifge", "ifgt",
+		"ifle", "if_icmpeq", "if_icmpne", "if_icmplt", "if_icmpge", "if_icmpgt", "if_icmple", "if_acmpeq", "if_acmpne",
+		"goto", "jsr", "ret", "tableswitch", "lookupswitch", "ireturn", "lreturn", "freturn", "dreturn", "areturn",
+		"return", "getstatic", "putstatic", "getfield", "putfield", "invokevirtual", "invokespecial", "invokestatic",
+		"invokeinterface", "invokedynamic", "new", "newarray", "anewarray", "arraylength", "athrow", "checkcast",
+		"instanceof", "monitorenter", "monitorexit", "wide", "multianewarray", "ifnull", "ifnonnull", "goto_w", "jsr_w"}
+	for i := range opcodes {
+		opcodes[i] = opcodeInfo{name: "invalid", operand: operandInvalid}
+	}
+	for i, name := range names {
+		opcodes[i] = opcodeInfo{name: name}
+	}
+	set := func(operand int, ops ...int) {
+		for _, op := range ops {
+			opcodes[op].operand = operand
+		}
+	}
+	set(operandByte, 0x10, OpNewArray)
+	set(operandShort, 0x11)
+	set(operandConst1, OpLdc)
+	set(operandConst2, OpLdcW, OpLdc2W, OpGetStatic, OpPutStatic, OpGetField, OpPutField, OpInvokeVirtual,
+		OpInvokeSpecial, OpInvokeStatic, OpNew, OpANewArray, OpCheckCast, OpInstanceOf)
+	set(operandLocal, 0x15, 0x16, 0x17, 0x18, 0x19, 0x36, 0x37, 0x38, 0x39, 0x3a, 0xa9)
+	for op := 0x99; op <= 0xa8; op++ {
+		set(operandBranch2, op)
+	}
+	set(operandBranch2, 0xc6, 0xc7)
+	set(operandBranch4, OpGotoW, OpJsrW)
+	set(operandIinc, OpIinc)
+	set(operandInvokeI, OpInvokeInterface)
+	set(operandInvokeD, OpInvokeDynamic)
+	set(operandMultiNew, OpMultiANewArray)
+	set(operandSwitch, OpTableSwitch, OpLookupSwitch)
+	set(operandWide, OpWide)
+}
+
+// OpcodeName 返回指令助记符
+func OpcodeName(opcode uint8) string {
+	return opcodes[opcode].name
+}
@@ -5,6 +5,9 @@ import (
 	"gopkg.in/yaml.v3"
 	"log/slog"
 	"os"
+	"pluto/job"
+	"pluto/util"
+	"time"
 )
 
 type Urls struct {
@@ -18,23 +21,83 @@ type Urls struct {
 }
 
 type JavaProgramConfig struct {
-	JavaParams       []string `yaml:"javaParams"`
+	JavaParams       []string `yaml:"javaParams" comment:"-Xmx is counted against limits.memoryBudget"`
 	DecompilerParams []string `yaml:"decompilerParams"`
+	Timeout          int      `yaml:"timeout" comment:"max running time in seconds, 0 for no limit"`
+}
+
+// CommandOptions 按配置的超时时间生成执行选项
+func (c JavaProgramConfig) CommandOptions(printErrorOnly bool) util.CommandOptions {
+	return util.CommandOptions{
+		PrintErrorOnly: printErrorOnly,
+		Timeout:        time.Duration(c.Timeout) * time.Second,
+	}
+}
+
+type LimitsConfig struct {
+	MemoryBudget int `yaml:"memoryBudget" comment:"MB of memory java programs may use at the same time, counted by their -Xmx, jobs wait in the queue until enough is free. 0 to check the available RAM before each job or program starts, -1 for no limit"`
+	CpuTime      int `yaml:"cpuTime" comment:"max CPU seconds of each java program, summed over all threads (Linux only), 0 for no limit"`
+	OpenFiles    int `yaml:"openFiles" comment:"max open files of each java program (Linux only), 0 for no limit"`
+}
+
+type WorkerConfig struct {
+	Workers     int                    `yaml:"workers" comment:"max jobs running at the same time"`
+	QueueLength int                    `yaml:"queueLength" comment:"max queued jobs, new ones are rejected with 503 when the queue is full"`
+	KindLimits  map[string]int         `yaml:"kindLimits" comment:"max running jobs of each kind: decompile (whole version), remap (jar only), class (single class), hierarchy (class hierarchy of a vanilla jar)"`
+	Retry       map[string]RetryConfig `yaml:"retry" comment:"retry policy of failed decompile, remap and hierarchy jobs"`
+}
+
+type RetryConfig struct {
+	MaxAttempts int      `yaml:"maxAttempts" comment:"max attempts including the first one, 1 for no retry"`
+	Backoff     int      `yaml:"backoff" comment:"seconds to wait before the first retry, doubled after each failed retry"`
+	MaxBackoff  int      `yaml:"maxBackoff" comment:"max seconds to wait between retries, 0 for no limit"`
+	Retryable   []string `yaml:"retryable" comment:"error classes to retry: network (download failures), timeout (java program timed out or exceeded limits.cpuTime), memory (java OutOfMemoryError), process (java program crashed). Other errors like unknown versions are never retried"`
+}
+
+// RetryPolicy 转换为任务的重试策略
+func (c RetryConfig) RetryPolicy() job.RetryPolicy {
+	return job.RetryPolicy{
+		MaxAttempts: c.MaxAttempts,
+		Backoff:     time.Duration(c.Backoff) * time.Second,
+		MaxBackoff:  time.Duration(c.MaxBackoff) * time.Second,
+		Retryable:   c.Retryable,
+	}
+}
+
+// ProcessLimits 转换为 util.ProcessLimits，内存预算为 0 时在运行程序前检查可用内存
+func (c LimitsConfig) ProcessLimits() util.ProcessLimits {
+	budget := int64(c.MemoryBudget) << 20
+	if c.MemoryBudget < 0 {
+		budget = -1
+	}
+	return util.ProcessLimits{
+		MemoryBudget: budget,
+		CpuTime:      time.Duration(c.CpuTime) * time.Second,
+		OpenFiles:    uint64(max(c.OpenFiles, 0)),
+	}
 }
 
 type ConfigObject struct {
-	Port       int               `yaml:"port" comment:"http server port"`
-	JavaPath   string            `yaml:"javaPath" comment:"executable java file for command"`
-	Urls       Urls              `yaml:"urls" comment:"if official source is too slow, try BMCLAPI: https://bmclapidoc.bangbang93.com/"`
-	Remapper   JavaProgramConfig `yaml:"remapper"`
-	Decompiler JavaProgramConfig `yaml:"decompiler"`
+	Port              int               `yaml:"port" comment:"http server port"`
+	AdminToken        string            `yaml:"adminToken" comment:"token for admin apis like cancelling jobs, sent as Authorization: Bearer <token>, admin apis are disabled if empty"`
+	JavaPath          string            `yaml:"javaPath" comment:"executable java file for command"`
+	CommandEncoding   string            `yaml:"commandEncoding" comment:"encoding of the output of java programs: auto (gbk on Windows, utf-8 elsewhere), utf-8 or gbk"`
+	Urls              Urls              `yaml:"urls" comment:"if official source is too slow, try BMCLAPI: https://bmclapidoc.bangbang93.com/"`
+	Remapper          JavaProgramConfig `yaml:"remapper"`
+	DefaultDecompiler string            `yaml:"defaultDecompiler" comment:"vineflower, cfr or procyon, can be overridden by the decompiler query parameter"`
+	Decompiler        JavaProgramConfig `yaml:"decompiler" comment:"vineflower"`
+	Cfr               JavaProgramConfig `yaml:"cfr"`
+	Procyon           JavaProgramConfig `yaml:"procyon"`
+	Worker            WorkerConfig      `yaml:"worker"`
+	Limits            LimitsConfig      `yaml:"limits"`
 }
 
 const configPath = "config.yml"
 
 var Config = ConfigObject{
-	Port:     5678,
-	JavaPath: "java",
+	Port:            5678,
+	JavaPath:        "java",
+	CommandEncoding: "auto",
 	Urls: Urls{
 		MavenCentral:       "https://repo1.maven.org/maven2",
 		MojangLauncherMeta: "https://launchermeta.mojang.com",
@@ -47,10 +110,38 @@ var Config = ConfigObject{
 	Remapper: JavaProgramConfig{
 		JavaParams:       []string{"-Xms2G", "-Xmx2G"},
 		DecompilerParams: []string{},
+		Timeout:          30 * 60,
 	},
 	Decompiler: JavaProgramConfig{
 		JavaParams:       []string{"-Xms2G", "-Xmx2G"},
 		DecompilerParams: []string{"--thread-count=1", "--skip-extra-files"},
+		Timeout:          3 * 60 * 60,
+	},
+	DefaultDecompiler: "vineflower",
+	Cfr: JavaProgramConfig{
+		JavaParams:       []string{"-Xms2G", "-Xmx2G"},
+		DecompilerParams: []string{"--silent", "true"},
+		Timeout:          3 * 60 * 60,
+	},
+	Procyon: JavaProgramConfig{
+		JavaParams:       []string{"-Xms2G", "-Xmx2G"},
+		DecompilerParams: []string{},
+		Timeout:          3 * 60 * 60,
+	},
+	Worker: WorkerConfig{
+		Workers:     4,
+		QueueLength: 100,
+		KindLimits: map[string]int{
+			"decompile": 1,
+			"remap":     2,
+			"class":     2,
+			"hierarchy": 2,
+		},
+		Retry: map[string]RetryConfig{
+			"decompile": {MaxAttempts: 3, Backoff: 60, MaxBackoff: 30 * 60, Retryable: []string{"network", "timeout", "memory"}},
+			"remap":     {MaxAttempts: 3, Backoff: 60, MaxBackoff: 30 * 60, Retryable: []string{"network", "timeout", "memory"}},
+			"hierarchy": {MaxAttempts: 3, Backoff: 60, MaxBackoff: 30 * 60, Retryable: []string{"network"}},
+		},
 	},
 }
 
@@ -36,6 +36,8 @@ const (
 	LibraryPath           = "libraries"
 	ClassPath             = LibraryPath + "/*"
 	DecompilerPath        = LibraryPath + "/vineflower.jar"
+	CfrPath               = LibraryPath + "/cfr.jar"
+	ProcyonMainClass      = "com.strobel.decompiler.DecompilerDriver"
 	TinyRemapperMainClass = "net.fabricmc.tinyremapper.Main"
 	ArtMainClass          = "net.neoforged.art.Main"
 	libraryConfigPath     = LibraryPath + "/versions.json"
@@ -65,6 +67,18 @@ func CheckLibrary() {
 			MavenArtifactID: "vineflower",
 			MavenRepoURL:    Config.Urls.MavenCentral,
 		},
+		{
+			Name:            "cfr.jar",
+			MavenGroupID:    "org.benf",
+			MavenArtifactID: "cfr",
+			MavenRepoURL:    Config.Urls.MavenCentral,
+		},
+		{
+			Name:            "procyon-decompiler.jar",
+			MavenGroupID:    "org.bitbucket.mstrobel",
+			MavenArtifactID: "procyon-decompiler",
+			MavenRepoURL:    Config.Urls.MavenCentral,
+		},
 		{
 			Name:            "tiny-remapper.jar",
 			MavenGroupID:    "net.fabricmc",
@@ -131,6 +145,19 @@ func CheckLibrary() {
 			MavenArtifactID: "cli-utils",
 			MavenRepoURL:    Config.Urls.NeoForgeMaven,
 		},
+		//Procyon dependencies
+		{
+			Name:            "procyon-core.jar",
+			MavenGroupID:    "org.bitbucket.mstrobel",
+			MavenArtifactID: "procyon-core",
+			MavenRepoURL:    Config.Urls.MavenCentral,
+		},
+		{
+			Name:            "procyon-compilertools.jar",
+			MavenGroupID:    "org.bitbucket.mstrobel",
+			MavenArtifactID: "procyon-compilertools",
+			MavenRepoURL:    Config.Urls.MavenCentral,
+		},
 	}
 
 	for _, tool := range toolsToUpdate {
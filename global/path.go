@@ -37,11 +37,29 @@ func GetRemappedPath(named Named, mcVersion string) string {
 	return CreatePathAndReturn(filepath.Join("cache", "remapped", named.GetName()), mcVersion+".jar")
 }
 
-func GetSourceFolder(named Named, mcVersion string) string {
-	path := filepath.Join("cache", "remapped", named.GetName(), mcVersion)
+// GetSourceFolder 每个反编译器的结果单独存放，Vineflower 沿用原来的目录
+func GetSourceFolder(named Named, mcVersion, decompiler string) string {
+	path := filepath.Join("cache", "remapped", named.GetName(), decompiledFolderName(mcVersion, decompiler))
 	err := os.MkdirAll(path, os.ModePerm)
 	if err != nil {
 		panic(err)
 	}
 	return path
 }
+
+// GetClassCacheFolder 单独反编译的类的缓存目录，与完整反编译的结果分开存放
+func GetClassCacheFolder(named Named, mcVersion, decompiler string) string {
+	path := filepath.Join("cache", "classes", named.GetName(), decompiledFolderName(mcVersion, decompiler))
+	err := os.MkdirAll(path, os.ModePerm)
+	if err != nil {
+		panic(err)
+	}
+	return path
+}
+
+func decompiledFolderName(mcVersion, decompiler string) string {
+	if decompiler != "" && decompiler != "vineflower" {
+		return mcVersion + "-" + decompiler
+	}
+	return mcVersion
+}
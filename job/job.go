@@ -0,0 +1,418 @@
+package job
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"os"
+	"pluto/util"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+type State string
+
+const (
+	StateQueued    State = "queued"
+	StateRunning   State = "running"
+	StateSucceeded State = "succeeded"
+	StateFailed    State = "failed"
+	StateCancelled State = "cancelled"
+)
+
+type Stage string
+
+const (
+	StageDownload  Stage = "download"
+	StageRemap     Stage = "remap"
+	StageDecompile Stage = "decompile"
+	StageIndex     Stage = "index"
+)
+
+type EventType string
+
+const (
+	EventState    EventType = "state"
+	EventStage    EventType = "stage"
+	EventProgress EventType = "progress"
+)
+
+// Event 任务状态变化时推送给订阅者，Job 为变化后的快照
+type Event struct {
+	Type EventType `json:"type"`
+	Job  Info      `json:"job"`
+}
+
+// Progress 当前阶段的进度，下载时为字节数，反编译时为类的数量
+type Progress struct {
+	Current int64  `json:"current"`
+	Total   int64  `json:"total"` // 未知时为 -1
+	Message string `json:"message,omitempty"`
+}
+
+// Key 标识一个任务的内容，同一时间相同 Key 的任务只会有一个在排队或运行
+type Key struct {
+	Kind        string `json:"kind"` // decompile、remap 或 hierarchy
+	Version     string `json:"version"`
+	MappingType string `json:"type"`
+	Decompiler  string `json:"decompiler,omitempty"`
+}
+
+func (k Key) String() string {
+	parts := []string{k.Kind}
+	for _, part := range []string{k.MappingType, k.Version, k.Decompiler} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// Info 任务状态的快照
+type Info struct {
+	ID string `json:"id"`
+	Key
+	Priority   util.Priority `json:"priority"`
+	State      State         `json:"state"`
+	Stage      Stage         `json:"stage,omitempty"`
+	Progress   *Progress     `json:"progress,omitempty"`
+	Error      string        `json:"error,omitempty"`
+	CreatedAt  time.Time     `json:"createdAt"`
+	StartedAt  *time.Time    `json:"startedAt,omitempty"`
+	FinishedAt *time.Time    `json:"finishedAt,omitempty"`
+	// Attempts 每次执行的记录，NextRetryAt 为失败后等待重试时下一次执行的时间
+	Attempts    []Attempt  `json:"attempts,omitempty"`
+	NextRetryAt *time.Time `json:"nextRetryAt,omitempty"`
+}
+
+type Job struct {
+	mu          sync.Mutex
+	info        Info
+	subscribers map[chan Event]struct{}
+	reported    time.Time // 上次推送进度的时间
+	cancel      context.CancelFunc
+	retryTimer  *time.Timer // 等待重试时不为 nil
+	logMu       sync.Mutex
+	logFile     *os.File
+}
+
+type contextKey struct{}
+
+const (
+	// 保留的已结束任务数量，超出时删除最早结束的
+	maxFinished = 1000
+	// 推送进度事件的最小间隔
+	progressInterval = 200 * time.Millisecond
+	// 订阅者的缓冲区大小，订阅者处理不及时时丢弃事件
+	subscriberBuffer = 64
+)
+
+var (
+	ErrNotFound = errors.New("cannot find job")
+	ErrFinished = errors.New("job has finished")
+
+	jobs     = map[string]*Job{}
+	active   = map[Key]*Job{}
+	jobsLock sync.Mutex
+)
+
+func (j *Job) Info() Info {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.info
+}
+
+func (j *Job) ID() string {
+	return j.info.ID
+}
+
+func (i Info) Finished() bool {
+	return i.State == StateSucceeded || i.State == StateFailed || i.State == StateCancelled
+}
+
+// Submit 创建任务并交给线程池执行，相同 Key 的任务已在排队或运行时直接返回该任务，队列已满时返回 util.ErrQueueFull
+func Submit(key Key, priority util.Priority) (*Job, error) {
+	if _, ok := getRunner(key.Kind); !ok {
+		return nil, errors.New("unknown job kind " + key.Kind)
+	}
+	jobsLock.Lock()
+	if j, ok := active[key]; ok {
+		jobsLock.Unlock()
+		return j, nil
+	}
+	j := &Job{
+		info: Info{
+			ID:        newID(),
+			Key:       key,
+			Priority:  priority,
+			State:     StateQueued,
+			CreatedAt: time.Now(),
+		},
+		subscribers: make(map[chan Event]struct{}),
+	}
+	if err := j.enqueue(); err != nil {
+		jobsLock.Unlock()
+		return nil, err
+	}
+	jobs[j.info.ID] = j
+	active[key] = j
+	pruneLocked()
+	jobsLock.Unlock()
+	save()
+	return j, nil
+}
+
+func (j *Job) enqueue() error {
+	return util.Submit(util.Task{Kind: j.info.Kind, Priority: j.info.Priority, Memory: j.memory(), Run: j.run})
+}
+
+func (j *Job) memory() int64 {
+	if runner, ok := getRunner(j.info.Kind); ok && runner.Memory != nil {
+		return runner.Memory(j.info.Key)
+	}
+	return 0
+}
+
+func (j *Job) run() (err error) {
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), contextKey{}, j))
+	defer cancel()
+	j.mu.Lock()
+	if j.info.Finished() {
+		// 排队时已被取消
+		j.mu.Unlock()
+		return nil
+	}
+	now := time.Now()
+	j.info.State, j.info.StartedAt = StateRunning, &now
+	j.startAttemptLocked(now)
+	j.cancel = cancel
+	j.publishLocked(EventState)
+	j.mu.Unlock()
+	save()
+	j.logf("Started %s, attempt %d", j.info.Key, len(j.info.Attempts))
+	defer func() {
+		if r := recover(); r != nil {
+			slog.Error("Job panicked", "id", j.ID(), "panic", r)
+			err = errors.New("internal error")
+		}
+		if ctx.Err() != nil {
+			err = context.Canceled
+		}
+		j.finish(err)
+	}()
+	runner, _ := getRunner(j.info.Kind)
+	ctx = util.WithReservedMemory(util.WithCommandLog(util.WithProgress(ctx, j.reportProgress), j.logLine), j.memory())
+	return runner.Run(ctx, j.info.Key)
+}
+
+// 结束当前执行，可以重试的错误会重新排队，否则任务结束
+func (j *Job) finish(err error) {
+	j.mu.Lock()
+	now := time.Now()
+	j.endAttemptLocked(now, err)
+	j.mu.Unlock()
+	if j.retry(err) {
+		return
+	}
+	j.mu.Lock()
+	if j.info.Finished() {
+		// 等待重试时被取消，又在重新排队时失败
+		j.mu.Unlock()
+		return
+	}
+	j.endLocked(now, err)
+	j.mu.Unlock()
+	j.deactivate()
+}
+
+// 将任务标记为结束并关闭所有订阅，需要持有 j.mu
+func (j *Job) endLocked(now time.Time, err error) {
+	if j.retryTimer != nil {
+		j.retryTimer.Stop()
+		j.retryTimer = nil
+	}
+	j.info.FinishedAt, j.info.Progress, j.info.NextRetryAt = &now, nil, nil
+	switch {
+	case errors.Is(err, context.Canceled):
+		j.info.State, j.info.Error = StateCancelled, "cancelled"
+	case err != nil:
+		j.info.State, j.info.Error = StateFailed, err.Error()
+	default:
+		j.info.State, j.info.Error = StateSucceeded, ""
+	}
+	j.logf("Finished: %s %s", j.info.State, j.info.Error)
+	j.closeLog()
+	j.publishLocked(EventState)
+	for ch := range j.subscribers {
+		close(ch)
+	}
+	j.subscribers, j.cancel = nil, nil
+}
+
+// 结束后不再占用 Key，相同的任务可以重新提交
+func (j *Job) deactivate() {
+	jobsLock.Lock()
+	if active[j.info.Key] == j {
+		delete(active, j.info.Key)
+	}
+	jobsLock.Unlock()
+	save()
+}
+
+// Cancel 取消任务，排队或等待重试的任务不会再执行，运行中的任务会被中断，外部程序会被杀死
+func Cancel(id string) (*Job, error) {
+	j, ok := Get(id)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	j.mu.Lock()
+	switch {
+	case j.info.Finished():
+		j.mu.Unlock()
+		return j, ErrFinished
+	case j.cancel != nil:
+		// 任务结束时才会更新状态
+		j.cancel()
+		j.mu.Unlock()
+	default:
+		// 在同一把锁内结束，之后 run 不会再启动该任务
+		j.endLocked(time.Now(), context.Canceled)
+		j.mu.Unlock()
+		j.deactivate()
+	}
+	return j, nil
+}
+
+// 修改任务状态并推送事件
+func (j *Job) update(eventType EventType, f func(info *Info)) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	f(&j.info)
+	j.publishLocked(eventType)
+}
+
+func (j *Job) publishLocked(eventType EventType) {
+	event := Event{Type: eventType, Job: j.info}
+	for ch := range j.subscribers {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+func (j *Job) reportProgress(current, total int64, message string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.info.Progress = &Progress{Current: current, Total: total, Message: message}
+	if now := time.Now(); current == total || now.Sub(j.reported) >= progressInterval {
+		j.reported = now
+		j.publishLocked(EventProgress)
+	}
+}
+
+// Subscribe 订阅任务的事件，首先收到当前状态，任务结束后 channel 会被关闭，不再需要时调用返回的函数取消订阅
+func (j *Job) Subscribe() (<-chan Event, func()) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	ch := make(chan Event, subscriberBuffer)
+	ch <- Event{Type: EventState, Job: j.info}
+	if j.info.Finished() {
+		close(ch)
+		return ch, func() {}
+	}
+	j.subscribers[ch] = struct{}{}
+	return ch, func() {
+		j.mu.Lock()
+		defer j.mu.Unlock()
+		if _, ok := j.subscribers[ch]; ok {
+			delete(j.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// FromContext 返回正在执行的任务，不在任务中执行时返回 nil
+func FromContext(ctx context.Context) *Job {
+	j, _ := ctx.Value(contextKey{}).(*Job)
+	return j
+}
+
+// SetStage 更新当前任务的阶段，不在任务中执行时忽略
+func SetStage(ctx context.Context, stage Stage) {
+	if j := FromContext(ctx); j != nil {
+		j.update(EventStage, func(info *Info) {
+			info.Stage, info.Progress = stage, nil
+		})
+		j.logf("Stage: %s", stage)
+		// 重启后根据阶段清理不完整的输出
+		save()
+	}
+}
+
+func Get(id string) (*Job, bool) {
+	jobsLock.Lock()
+	defer jobsLock.Unlock()
+	j, ok := jobs[id]
+	return j, ok
+}
+
+// Active 返回相同 Key 正在排队或运行的任务
+func Active(key Key) (*Job, bool) {
+	jobsLock.Lock()
+	defer jobsLock.Unlock()
+	j, ok := active[key]
+	return j, ok
+}
+
+// List 返回满足条件的任务快照，按创建时间从新到旧排序
+func List(filter func(info Info) bool) []Info {
+	jobsLock.Lock()
+	all := make([]*Job, 0, len(jobs))
+	for _, j := range jobs {
+		all = append(all, j)
+	}
+	jobsLock.Unlock()
+	result := make([]Info, 0, len(all))
+	for _, j := range all {
+		if info := j.Info(); filter == nil || filter(info) {
+			result = append(result, info)
+		}
+	}
+	sort.Slice(result, func(a, b int) bool {
+		return result[a].CreatedAt.After(result[b].CreatedAt)
+	})
+	return result
+}
+
+// 删除最早结束的任务，需要持有 jobsLock
+func pruneLocked() {
+	var finished []Info
+	for _, j := range jobs {
+		if info := j.Info(); info.Finished() {
+			finished = append(finished, info)
+		}
+	}
+	if len(finished) <= maxFinished {
+		return
+	}
+	sort.Slice(finished, func(a, b int) bool {
+		return finished[a].FinishedAt.Before(*finished[b].FinishedAt)
+	})
+	for _, info := range finished[:len(finished)-maxFinished] {
+		delete(jobs, info.ID)
+		if err := os.Remove(LogPath(info.ID)); err != nil && !os.IsNotExist(err) {
+			slog.Error("Failed to remove job log: " + err.Error())
+		}
+	}
+}
+
+func newID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
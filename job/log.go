@@ -0,0 +1,49 @@
+package job
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const logFolder = "cache/jobs"
+
+// LogPath 返回任务日志的路径，任务开始运行后才会创建
+func LogPath(id string) string {
+	return filepath.Join(logFolder, id+".log")
+}
+
+// logf 向任务日志追加一行带时间戳的记录，日志在第一次写入时打开
+func (j *Job) logf(format string, args ...any) {
+	j.logMu.Lock()
+	defer j.logMu.Unlock()
+	if j.logFile == nil {
+		if err := os.MkdirAll(logFolder, os.ModePerm); err != nil {
+			slog.Error("Failed to create job log: " + err.Error())
+			return
+		}
+		file, err := os.OpenFile(LogPath(j.ID()), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			slog.Error("Failed to create job log: " + err.Error())
+			return
+		}
+		j.logFile = file
+	}
+	_, _ = fmt.Fprintf(j.logFile, "%s %s\n", time.Now().Format("2006-01-02 15:04:05.000"), fmt.Sprintf(format, args...))
+}
+
+// 外部程序的输出
+func (j *Job) logLine(line string) {
+	j.logf("%s", line)
+}
+
+func (j *Job) closeLog() {
+	j.logMu.Lock()
+	defer j.logMu.Unlock()
+	if j.logFile != nil {
+		_ = j.logFile.Close()
+		j.logFile = nil
+	}
+}
@@ -0,0 +1,106 @@
+package job
+
+import (
+	"context"
+	"errors"
+	"pluto/util"
+	"slices"
+	"time"
+)
+
+// RetryPolicy 任务失败后的重试策略，MaxAttempts 不大于 1 时不重试
+type RetryPolicy struct {
+	MaxAttempts int           // 包括第一次在内的最大尝试次数
+	Backoff     time.Duration // 第一次重试前的等待时间，之后每次翻倍
+	MaxBackoff  time.Duration // 等待时间的上限，0 表示不限制
+	Retryable   []string      // 可以重试的错误类别，见 util.ErrorClass
+}
+
+// Attempt 一次执行的记录
+type Attempt struct {
+	StartedAt  time.Time  `json:"startedAt"`
+	FinishedAt *time.Time `json:"finishedAt,omitempty"`
+	Error      string     `json:"error,omitempty"`
+	ErrorClass string     `json:"errorClass,omitempty"` // 为空时是永久错误，不会重试
+}
+
+// 第 failed 次失败后的等待时间
+func (p RetryPolicy) delay(failed int) time.Duration {
+	d := p.Backoff
+	for i := 1; i < failed && d > 0; i++ {
+		if p.MaxBackoff > 0 && d >= p.MaxBackoff {
+			break
+		}
+		d *= 2
+	}
+	if p.MaxBackoff > 0 && d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+	return max(d, 0)
+}
+
+// 开始新的一次执行，需要持有 j.mu，快照共享 Attempts，所以不修改原来的数组
+func (j *Job) startAttemptLocked(now time.Time) {
+	j.info.Attempts = append(slices.Clip(j.info.Attempts), Attempt{StartedAt: now})
+	j.info.NextRetryAt = nil
+}
+
+// 记录当前执行的结果，需要持有 j.mu
+func (j *Job) endAttemptLocked(now time.Time, err error) {
+	last := len(j.info.Attempts) - 1
+	if last < 0 || j.info.Attempts[last].FinishedAt != nil {
+		return
+	}
+	attempts := slices.Clone(j.info.Attempts)
+	attempts[last].FinishedAt = &now
+	if err != nil {
+		attempts[last].Error, attempts[last].ErrorClass = err.Error(), util.ErrorClass(err)
+	}
+	j.info.Attempts = attempts
+}
+
+// 按重试策略安排下一次执行，不应重试时返回 false
+func (j *Job) retry(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	runner, ok := getRunner(j.info.Kind)
+	if !ok {
+		return false
+	}
+	policy, class := runner.Retry, util.ErrorClass(err)
+	j.mu.Lock()
+	failed := len(j.info.Attempts)
+	if j.info.Finished() || failed >= policy.MaxAttempts || class == "" || !slices.Contains(policy.Retryable, class) {
+		j.mu.Unlock()
+		return false
+	}
+	delay := policy.delay(failed)
+	next := time.Now().Add(delay)
+	j.info.State, j.info.Stage, j.info.Progress, j.info.Error = StateQueued, "", nil, err.Error()
+	j.info.NextRetryAt, j.cancel = &next, nil
+	j.logf("Attempt %d of %d failed (%s), retry in %s", failed, policy.MaxAttempts, class, delay)
+	j.publishLocked(EventState)
+	j.mu.Unlock()
+	save()
+	j.schedule(next)
+	return true
+}
+
+// 到达 at 时重新排队，等待期间被取消时不再排队
+func (j *Job) schedule(at time.Time) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.retryTimer = time.AfterFunc(time.Until(at), func() {
+		j.mu.Lock()
+		j.retryTimer = nil
+		cancelled := j.info.Finished()
+		j.mu.Unlock()
+		if cancelled {
+			return
+		}
+		if err := j.enqueue(); err != nil {
+			j.finish(err)
+		}
+	})
+}
@@ -0,0 +1,114 @@
+package job
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"pluto/util"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Runner 执行某一类任务
+type Runner struct {
+	Run func(ctx context.Context, key Key) error
+	// Discard 删除被中断的任务留下的不完整输出，stage 为中断时所处的阶段，可以为 nil
+	Discard func(key Key, stage Stage) error
+	Retry   RetryPolicy
+	// Memory 返回任务需要预留的内存，字节，线程池在内存预算足够时才开始执行，可以为 nil
+	Memory func(key Key) int64
+}
+
+const storePath = "cache/jobs.json"
+
+var (
+	runners     = map[string]Runner{}
+	runnersLock sync.RWMutex
+	saveLock    sync.Mutex
+)
+
+// Register 注册任务类型，需要在 Restore 和 Submit 之前调用
+func Register(kind string, runner Runner) {
+	runnersLock.Lock()
+	defer runnersLock.Unlock()
+	runners[kind] = runner
+}
+
+func getRunner(kind string) (Runner, bool) {
+	runnersLock.RLock()
+	defer runnersLock.RUnlock()
+	runner, ok := runners[kind]
+	return runner, ok
+}
+
+// Restore 读取上次保存的任务，上次运行中的任务会先清理不完整的输出，然后与排队中的任务一起重新排队，
+// 等待重试的任务到时间后再排队
+func Restore() error {
+	infos, err := util.LoadConfig[[]Info](storePath)
+	if err != nil {
+		return err
+	}
+	var interrupted []*Job
+	jobsLock.Lock()
+	for _, info := range infos {
+		j := &Job{info: info, subscribers: make(map[chan Event]struct{})}
+		jobs[info.ID] = j
+		if info.Finished() {
+			continue
+		}
+		runner, ok := getRunner(info.Kind)
+		if !ok {
+			now := time.Now()
+			j.info.State, j.info.Error, j.info.FinishedAt = StateFailed, "unknown job kind "+info.Kind, &now
+			continue
+		}
+		if info.State == StateRunning {
+			j.logf("Interrupted by restart during stage %s", info.Stage)
+			j.endAttemptLocked(time.Now(), errors.New("interrupted by restart"))
+			if runner.Discard != nil {
+				if err := runner.Discard(info.Key, info.Stage); err != nil {
+					slog.Error("Failed to discard partial output of job " + info.ID + ": " + err.Error())
+				}
+			}
+		}
+		j.info.State, j.info.Stage, j.info.Progress, j.info.StartedAt = StateQueued, "", nil, nil
+		active[info.Key] = j
+		interrupted = append(interrupted, j)
+	}
+	jobsLock.Unlock()
+	save()
+	sort.Slice(interrupted, func(a, b int) bool {
+		return interrupted[a].info.CreatedAt.Before(interrupted[b].info.CreatedAt)
+	})
+	for _, j := range interrupted {
+		if next := j.info.NextRetryAt; next != nil {
+			j.schedule(*next)
+			continue
+		}
+		slog.Info("Re-enqueue interrupted job " + j.info.ID + " (" + j.info.Key.String() + ")")
+		if err := j.enqueue(); err != nil {
+			j.finish(err)
+		}
+	}
+	return nil
+}
+
+// 保存所有任务的状态，进度不会保存
+func save() {
+	saveLock.Lock()
+	defer saveLock.Unlock()
+	infos := List(nil)
+	for i := range infos {
+		infos[i].Progress = nil
+	}
+	if err := os.MkdirAll(filepath.Dir(storePath), os.ModePerm); err != nil {
+		slog.Error("Failed to save " + storePath + ": " + err.Error())
+		return
+	}
+	if err := util.SaveConfig(infos, storePath); err != nil {
+		slog.Error("Failed to save " + storePath + ": " + err.Error())
+	}
+}
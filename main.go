@@ -20,6 +20,12 @@ func main() {
 	if err != nil {
 		log.Fatal(err)
 	}
+	err = util.SetCommandEncoding(global.Config.CommandEncoding)
+	if err != nil {
+		log.Fatal(err)
+	}
+	util.SetProcessLimits(global.Config.Limits.ProcessLimits())
+	util.InitWorkers(global.Config.Worker.Workers, global.Config.Worker.QueueLength, global.Config.Worker.KindLimits)
 	err = mapping.InitMappingConfig()
 	if err != nil {
 		log.Fatal(err)
@@ -30,6 +36,11 @@ func main() {
 	}
 	//Libraries
 	global.CheckLibrary()
+	//Jobs
+	err = mapping.InitJobs()
+	if err != nil {
+		slog.Error("Failed to restore jobs: " + err.Error())
+	}
 	//Main Logic
 	err = webserver.Launch()
 	if err != nil {
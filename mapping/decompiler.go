@@ -0,0 +1,55 @@
+package mapping
+
+import (
+	"context"
+	"errors"
+	"pluto/global"
+	"pluto/mapping/decompilers"
+	"pluto/util"
+	"sort"
+)
+
+type Decompiler interface {
+	GetName() string
+	Decompile(ctx context.Context, jarPath, outputFolder string) error             // 进度通过 util.ReportProgress 报告
+	DecompileClass(ctx context.Context, jarPath, class, outputFolder string) error // class 为 jar 中的类路径，如 net/minecraft/Foo
+}
+
+var decompilerMap = map[string]Decompiler{
+	"vineflower": &decompilers.Vineflower{},
+	"cfr":        &decompilers.Cfr{},
+	"procyon":    &decompilers.Procyon{},
+}
+
+// GetDecompiler 按名称获取反编译器，名称为空时使用配置中的默认反编译器
+func GetDecompiler(name string) (Decompiler, error) {
+	if name == "" {
+		name = global.Config.DefaultDecompiler
+	}
+	decompiler, ok := decompilerMap[name]
+	if !ok {
+		return nil, errors.New("unknown decompiler " + name)
+	}
+	return decompiler, nil
+}
+
+// 反编译器的 Java 参数中 -Xmx 指定的内存
+func decompilerMemory(name string) int64 {
+	config := global.Config.Decompiler
+	switch name {
+	case "cfr":
+		config = global.Config.Cfr
+	case "procyon":
+		config = global.Config.Procyon
+	}
+	return util.ParseJavaMemory(config.JavaParams)
+}
+
+func GetDecompilerNames() []string {
+	names := make([]string, 0, len(decompilerMap))
+	for name := range decompilerMap {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
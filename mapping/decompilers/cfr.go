@@ -0,0 +1,31 @@
+package decompilers
+
+import (
+	"context"
+	"os"
+	"pluto/global"
+	"pluto/util"
+)
+
+type Cfr struct{}
+
+func (d *Cfr) GetName() string {
+	return "cfr"
+}
+
+func (d *Cfr) Decompile(ctx context.Context, jarPath, outputFolder string) error {
+	config := global.Config.Cfr
+	params := util.ConcatMultipleSlices([][]string{config.JavaParams, {"-jar", global.CfrPath, jarPath, "--outputdir", outputFolder}, config.DecompilerParams})
+	return util.RunCommand(ctx, global.Config.JavaPath, params, config.CommandOptions(true))
+}
+
+func (d *Cfr) DecompileClass(ctx context.Context, jarPath, class, outputFolder string) error {
+	input, err := extractClass(jarPath, class)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(input)
+	config := global.Config.Cfr
+	params := util.ConcatMultipleSlices([][]string{config.JavaParams, {"-jar", global.CfrPath, input, "--extraclasspath", jarPath, "--outputdir", outputFolder}, config.DecompilerParams})
+	return util.RunCommand(ctx, global.Config.JavaPath, params, config.CommandOptions(true))
+}
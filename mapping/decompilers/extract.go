@@ -0,0 +1,51 @@
+package decompilers
+
+import (
+	"archive/zip"
+	"os"
+	"strings"
+)
+
+// extractClass 将 jar 中的 class 及其内部类复制到临时 jar 中，返回临时 jar 路径，用完需要删除
+func extractClass(jarPath, class string) (string, error) {
+	jar, err := zip.OpenReader(jarPath)
+	if err != nil {
+		return "", err
+	}
+	defer jar.Close()
+	file, err := os.CreateTemp("", "pluto-class-*.jar")
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	archive := zip.NewWriter(file)
+	for _, entry := range jar.File {
+		if entry.Name == class+".class" || strings.HasPrefix(entry.Name, class+"$") && strings.HasSuffix(entry.Name, ".class") {
+			if err := archive.Copy(entry); err != nil {
+				os.Remove(file.Name())
+				return "", err
+			}
+		}
+	}
+	if err := archive.Close(); err != nil {
+		os.Remove(file.Name())
+		return "", err
+	}
+	return file.Name(), nil
+}
+
+// countOuterClasses 统计 jar 中的顶层类数量，用于计算反编译进度
+func countOuterClasses(jarPath string) (int64, error) {
+	jar, err := zip.OpenReader(jarPath)
+	if err != nil {
+		return 0, err
+	}
+	defer jar.Close()
+	var count int64
+	for _, entry := range jar.File {
+		if strings.HasSuffix(entry.Name, ".class") && !strings.Contains(entry.Name, "$") {
+			count++
+		}
+	}
+	return count, nil
+}
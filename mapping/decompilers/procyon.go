@@ -0,0 +1,28 @@
+package decompilers
+
+import (
+	"context"
+	"path/filepath"
+	"pluto/global"
+	"pluto/util"
+)
+
+type Procyon struct{}
+
+func (d *Procyon) GetName() string {
+	return "procyon"
+}
+
+func (d *Procyon) Decompile(ctx context.Context, jarPath, outputFolder string) error {
+	config := global.Config.Procyon
+	params := util.ConcatMultipleSlices([][]string{config.JavaParams, {"-cp", global.ClassPath, global.ProcyonMainClass}, config.DecompilerParams, {"-jar", jarPath, "-o", outputFolder}})
+	return util.RunCommand(ctx, global.Config.JavaPath, params, config.CommandOptions(true))
+}
+
+// DecompileClass Procyon 可以直接按类名反编译，内部类会一起输出
+func (d *Procyon) DecompileClass(ctx context.Context, jarPath, class, outputFolder string) error {
+	config := global.Config.Procyon
+	classPath := global.ClassPath + string(filepath.ListSeparator) + jarPath
+	params := util.ConcatMultipleSlices([][]string{config.JavaParams, {"-cp", classPath, global.ProcyonMainClass}, config.DecompilerParams, {"-o", outputFolder, class}})
+	return util.RunCommand(ctx, global.Config.JavaPath, params, config.CommandOptions(true))
+}
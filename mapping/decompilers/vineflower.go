@@ -0,0 +1,46 @@
+package decompilers
+
+import (
+	"context"
+	"os"
+	"pluto/global"
+	"pluto/util"
+	"strings"
+	"sync/atomic"
+)
+
+type Vineflower struct{}
+
+func (d *Vineflower) GetName() string {
+	return "vineflower"
+}
+
+// Decompile 反编译整个 jar，通过 Vineflower 输出的 "Decompiling class" 行报告进度
+func (d *Vineflower) Decompile(ctx context.Context, jarPath, outputFolder string) error {
+	config := global.Config.Decompiler
+	params := util.ConcatMultipleSlices([][]string{config.JavaParams, {"-jar", global.DecompilerPath}, config.DecompilerParams, {jarPath, outputFolder}})
+	total, err := countOuterClasses(jarPath)
+	if err != nil {
+		total = -1
+	}
+	var decompiled atomic.Int64
+	options := config.CommandOptions(true)
+	options.OnLine = func(line string) {
+		if _, class, ok := strings.Cut(line, "Decompiling class "); ok {
+			util.ReportProgress(ctx, decompiled.Add(1), total, strings.TrimSpace(class))
+		}
+	}
+	return util.RunCommand(ctx, global.Config.JavaPath, params, options)
+}
+
+// DecompileClass 只反编译 class 及其内部类，jar 中其余的类作为依赖库
+func (d *Vineflower) DecompileClass(ctx context.Context, jarPath, class, outputFolder string) error {
+	input, err := extractClass(jarPath, class)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(input)
+	config := global.Config.Decompiler
+	params := util.ConcatMultipleSlices([][]string{config.JavaParams, {"-jar", global.DecompilerPath}, config.DecompilerParams, {"-e=" + jarPath, input, outputFolder}})
+	return util.RunCommand(ctx, global.Config.JavaPath, params, config.CommandOptions(true))
+}
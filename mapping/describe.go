@@ -0,0 +1,108 @@
+package mapping
+
+import (
+	"log/slog"
+	"pluto/mapping/java"
+	"sort"
+	"strings"
+)
+
+func GetMappingTypes() []string {
+	types := make([]string, 0, len(serviceMap))
+	for name := range serviceMap {
+		types = append(types, name)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// NewDescriber 返回一个函数，用于查询某个映射中的类或成员在 notch 和其它映射中的名称，
+// 所有映射会预先加载，加载失败的映射将被跳过
+func NewDescriber(mcVersion, mappingType string) (func(class, member string) string, error) {
+	current, err := LoadMapping(mcVersion, mappingType)
+	if err != nil {
+		return nil, err
+	}
+	others := make(map[string]*java.Mappings)
+	for _, t := range GetMappingTypes() {
+		if t == mappingType {
+			continue
+		}
+		m, err := LoadMapping(mcVersion, t)
+		if err != nil {
+			slog.Warn("Skip mapping " + t + " for describing: " + err.Error())
+			continue
+		}
+		others[t] = m
+	}
+	types := make([]string, 0, len(others))
+	for t := range others {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+
+	return func(class, member string) string {
+		var notches []java.SingleInfo
+		for _, named := range current.NamedByName[nameOrSimpleName(class, member)] {
+			if isDescribed(named, class, member) {
+				notches = append(notches, current.NamedToNotch[named])
+			}
+		}
+		if len(notches) == 0 {
+			return ""
+		}
+		lines := []string{"notch: " + distinctNames(notches, func(notch java.SingleInfo) (java.SingleInfo, bool) { return notch, true })}
+		for _, t := range types {
+			names := distinctNames(notches, func(notch java.SingleInfo) (java.SingleInfo, bool) {
+				return others[t].ResolveMember(notch, nil)
+			})
+			if names != "" {
+				lines = append(lines, t+": "+names)
+			}
+		}
+		return strings.Join(lines, "\n")
+	}, nil
+}
+
+func nameOrSimpleName(class, member string) string {
+	if member != "" {
+		return member
+	}
+	return java.FullToClassName(class)
+}
+
+// 成员所在类可以是 class 本身或其内部类
+func isDescribed(named java.SingleInfo, class, member string) bool {
+	if member == "" {
+		return named.Type == "class" && named.Class == class
+	}
+	owner := java.NormalizeClassName(named.Class)
+	return named.Type != "class" && (owner == class || strings.HasPrefix(owner, class+"$"))
+}
+
+func distinctNames(notches []java.SingleInfo, translate func(notch java.SingleInfo) (java.SingleInfo, bool)) string {
+	var names []string
+	for _, notch := range notches {
+		info, ok := translate(notch)
+		if !ok {
+			continue
+		}
+		name := info.Name
+		if info.Type == "class" {
+			name = info.Class
+		}
+		if !contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	return strings.Join(names, ", ")
+}
+
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
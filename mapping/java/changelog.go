@@ -0,0 +1,351 @@
+package java
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+type MemberRef struct {
+	Class      string `json:"class"`
+	Name       string `json:"name"`
+	Descriptor string `json:"descriptor"`
+}
+
+type Rename struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+type MemberRename struct {
+	Class      string `json:"class"`
+	From       string `json:"from"`
+	To         string `json:"to"`
+	Descriptor string `json:"descriptor"`
+}
+
+type DescriptorChange struct {
+	Class string `json:"class"`
+	Name  string `json:"name"`
+	From  string `json:"from"`
+	To    string `json:"to"`
+}
+
+type PackageChanges struct {
+	Package            string             `json:"package"`
+	AddedClasses       []string           `json:"addedClasses,omitempty"`
+	RemovedClasses     []string           `json:"removedClasses,omitempty"`
+	RenamedClasses     []Rename           `json:"renamedClasses,omitempty"`
+	AddedMethods       []MemberRef        `json:"addedMethods,omitempty"`
+	RemovedMethods     []MemberRef        `json:"removedMethods,omitempty"`
+	RenamedMethods     []MemberRename     `json:"renamedMethods,omitempty"`
+	ChangedDescriptors []DescriptorChange `json:"changedDescriptors,omitempty"`
+	AddedFields        []MemberRef        `json:"addedFields,omitempty"`
+	RemovedFields      []MemberRef        `json:"removedFields,omitempty"`
+	RenamedFields      []MemberRename     `json:"renamedFields,omitempty"`
+}
+
+type Changelog struct {
+	From     string            `json:"from"`
+	To       string            `json:"to"`
+	Packages []*PackageChanges `json:"packages"`
+}
+
+// 某个类下的成员，key 为 名称+描述符
+type classMembers struct {
+	methods map[string]MemberRef
+	fields  map[string]MemberRef
+}
+
+func collectMembers(m *Mappings) map[string]*classMembers {
+	classes := make(map[string]*classMembers)
+	get := func(class string) *classMembers {
+		if c, ok := classes[class]; ok {
+			return c
+		}
+		c := &classMembers{methods: make(map[string]MemberRef), fields: make(map[string]MemberRef)}
+		classes[class] = c
+		return c
+	}
+	for _, named := range m.NotchToNamed {
+		class := NormalizeClassName(named.Class)
+		switch named.Type {
+		case "class":
+			get(class)
+		case "method":
+			get(class).methods[named.Name+named.Signature] = MemberRef{Class: class, Name: named.Name, Descriptor: named.Signature}
+		case "field":
+			get(class).fields[named.Name+":"+named.Signature] = MemberRef{Class: class, Name: named.Name, Descriptor: named.Signature}
+		}
+	}
+	return classes
+}
+
+// Diff 比较同一映射下两个版本的 API，重命名通过简单名相同或成员完全相同来推断
+func Diff(fromVersion, toVersion string, from, to *Mappings) *Changelog {
+	fromClasses, toClasses := collectMembers(from), collectMembers(to)
+	packages := make(map[string]*PackageChanges)
+	pkg := func(class string) *PackageChanges {
+		name := ""
+		if idx := strings.LastIndexByte(class, '.'); idx >= 0 {
+			name = class[:idx]
+		}
+		if p, ok := packages[name]; ok {
+			return p
+		}
+		p := &PackageChanges{Package: name}
+		packages[name] = p
+		return p
+	}
+
+	var removed, added []string
+	for class := range fromClasses {
+		if _, ok := toClasses[class]; !ok {
+			removed = append(removed, class)
+		}
+	}
+	for class := range toClasses {
+		if _, ok := fromClasses[class]; !ok {
+			added = append(added, class)
+		}
+	}
+	sort.Strings(removed)
+	sort.Strings(added)
+
+	renames := matchClassRenames(removed, added, fromClasses, toClasses)
+	for _, class := range removed {
+		if _, ok := renames[class]; !ok {
+			pkg(class).RemovedClasses = append(pkg(class).RemovedClasses, class)
+		}
+	}
+	renamedTo := make(map[string]struct{})
+	for oldName, newName := range renames {
+		renamedTo[newName] = struct{}{}
+		p := pkg(newName)
+		p.RenamedClasses = append(p.RenamedClasses, Rename{From: oldName, To: newName})
+	}
+	for _, class := range added {
+		if _, ok := renamedTo[class]; !ok {
+			pkg(class).AddedClasses = append(pkg(class).AddedClasses, class)
+		}
+	}
+
+	for oldName, oldMembers := range fromClasses {
+		newName := oldName
+		if renamed, ok := renames[oldName]; ok {
+			newName = renamed
+		}
+		newMembers, ok := toClasses[newName]
+		if !ok {
+			continue
+		}
+		p := pkg(newName)
+		diffMembers(newName, oldMembers.methods, newMembers.methods, &p.AddedMethods, &p.RemovedMethods, &p.RenamedMethods, &p.ChangedDescriptors)
+		var fieldDescriptors []DescriptorChange
+		diffMembers(newName, oldMembers.fields, newMembers.fields, &p.AddedFields, &p.RemovedFields, &p.RenamedFields, &fieldDescriptors)
+		// 字段类型变化同样记为描述符变化
+		p.ChangedDescriptors = append(p.ChangedDescriptors, fieldDescriptors...)
+	}
+
+	changelog := &Changelog{From: fromVersion, To: toVersion, Packages: []*PackageChanges{}}
+	for _, p := range packages {
+		if p.isEmpty() {
+			continue
+		}
+		p.sort()
+		changelog.Packages = append(changelog.Packages, p)
+	}
+	sort.Slice(changelog.Packages, func(i, j int) bool {
+		return changelog.Packages[i].Package < changelog.Packages[j].Package
+	})
+	return changelog
+}
+
+// 简单名唯一相同（移动包）或成员集合完全相同时视为重命名
+func matchClassRenames(removed, added []string, fromClasses, toClasses map[string]*classMembers) map[string]string {
+	renames := make(map[string]string)
+	used := make(map[string]struct{})
+	bySimple := make(map[string][]string)
+	for _, class := range added {
+		bySimple[FullToClassName(class)] = append(bySimple[FullToClassName(class)], class)
+	}
+	removedBySimple := make(map[string]int)
+	for _, class := range removed {
+		removedBySimple[FullToClassName(class)]++
+	}
+	bySignature := make(map[string][]string)
+	for _, class := range added {
+		if sig := memberSignature(toClasses[class]); sig != "" {
+			bySignature[sig] = append(bySignature[sig], class)
+		}
+	}
+	for _, class := range removed {
+		// 两边的简单名都唯一时才能确定对应关系
+		simple := FullToClassName(class)
+		if candidates := bySimple[simple]; len(candidates) == 1 && removedBySimple[simple] == 1 {
+			if _, ok := used[candidates[0]]; !ok {
+				renames[class] = candidates[0]
+				used[candidates[0]] = struct{}{}
+			}
+		}
+	}
+	for _, class := range removed {
+		if _, ok := renames[class]; ok {
+			continue
+		}
+		candidates := bySignature[memberSignature(fromClasses[class])]
+		if len(candidates) != 1 {
+			continue
+		}
+		if _, ok := used[candidates[0]]; !ok {
+			renames[class] = candidates[0]
+			used[candidates[0]] = struct{}{}
+		}
+	}
+	return renames
+}
+
+func memberSignature(members *classMembers) string {
+	keys := make([]string, 0, len(members.methods)+len(members.fields))
+	for key := range members.methods {
+		keys = append(keys, key)
+	}
+	for key := range members.fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return strings.Join(keys, "\n")
+}
+
+// 同名且各只剩一个的视为描述符变化，描述符相同且各只剩一个的视为重命名
+func diffMembers(class string, from, to map[string]MemberRef, added, removed *[]MemberRef, renamed *[]MemberRename, changed *[]DescriptorChange) {
+	var gone, fresh []MemberRef
+	for key, member := range from {
+		if _, ok := to[key]; !ok {
+			gone = append(gone, member)
+		}
+	}
+	for key, member := range to {
+		if _, ok := from[key]; !ok {
+			fresh = append(fresh, member)
+		}
+	}
+	matched := make(map[int]struct{})
+	freshMatched := make(map[int]struct{})
+	pair := func(key func(MemberRef) string, onMatch func(old, new MemberRef)) {
+		goneBy, freshBy := make(map[string][]int), make(map[string][]int)
+		for i, m := range gone {
+			if _, ok := matched[i]; !ok {
+				goneBy[key(m)] = append(goneBy[key(m)], i)
+			}
+		}
+		for i, m := range fresh {
+			if _, ok := freshMatched[i]; !ok {
+				freshBy[key(m)] = append(freshBy[key(m)], i)
+			}
+		}
+		for k, olds := range goneBy {
+			if news := freshBy[k]; len(olds) == 1 && len(news) == 1 {
+				matched[olds[0]], freshMatched[news[0]] = struct{}{}, struct{}{}
+				onMatch(gone[olds[0]], fresh[news[0]])
+			}
+		}
+	}
+	pair(func(m MemberRef) string { return m.Name }, func(old, new MemberRef) {
+		*changed = append(*changed, DescriptorChange{Class: class, Name: new.Name, From: old.Descriptor, To: new.Descriptor})
+	})
+	pair(func(m MemberRef) string { return m.Descriptor }, func(old, new MemberRef) {
+		*renamed = append(*renamed, MemberRename{Class: class, From: old.Name, To: new.Name, Descriptor: new.Descriptor})
+	})
+	for i, m := range gone {
+		if _, ok := matched[i]; !ok {
+			m.Class = class
+			*removed = append(*removed, m)
+		}
+	}
+	for i, m := range fresh {
+		if _, ok := freshMatched[i]; !ok {
+			*added = append(*added, m)
+		}
+	}
+}
+
+func (p *PackageChanges) isEmpty() bool {
+	return len(p.AddedClasses)+len(p.RemovedClasses)+len(p.RenamedClasses)+len(p.AddedMethods)+len(p.RemovedMethods)+
+		len(p.RenamedMethods)+len(p.ChangedDescriptors)+len(p.AddedFields)+len(p.RemovedFields)+len(p.RenamedFields) == 0
+}
+
+func (p *PackageChanges) sort() {
+	sort.Strings(p.AddedClasses)
+	sort.Strings(p.RemovedClasses)
+	sort.Slice(p.RenamedClasses, func(i, j int) bool { return p.RenamedClasses[i].From < p.RenamedClasses[j].From })
+	for _, refs := range []*[]MemberRef{&p.AddedMethods, &p.RemovedMethods, &p.AddedFields, &p.RemovedFields} {
+		sort.Slice(*refs, func(i, j int) bool { return (*refs)[i].String() < (*refs)[j].String() })
+	}
+	for _, renames := range []*[]MemberRename{&p.RenamedMethods, &p.RenamedFields} {
+		sort.Slice(*renames, func(i, j int) bool {
+			return (*renames)[i].Class+"#"+(*renames)[i].From < (*renames)[j].Class+"#"+(*renames)[j].From
+		})
+	}
+	sort.Slice(p.ChangedDescriptors, func(i, j int) bool {
+		return p.ChangedDescriptors[i].Class+"#"+p.ChangedDescriptors[i].Name < p.ChangedDescriptors[j].Class+"#"+p.ChangedDescriptors[j].Name
+	})
+}
+
+func (m MemberRef) String() string {
+	return m.Class + "#" + m.Name + " " + m.Descriptor
+}
+
+// Markdown 将变更按包输出为 Markdown 文档
+func (c *Changelog) Markdown(mappingType string) string {
+	var out strings.Builder
+	fmt.Fprintf(&out, "# API changes (%s): %s → %s\n", mappingType, c.From, c.To)
+	if len(c.Packages) == 0 {
+		out.WriteString("\nNo changes.\n")
+	}
+	for _, p := range c.Packages {
+		name := p.Package
+		if name == "" {
+			name = "(default package)"
+		}
+		fmt.Fprintf(&out, "\n## `%s`\n", name)
+		section := func(title string, items []string) {
+			if len(items) == 0 {
+				return
+			}
+			fmt.Fprintf(&out, "\n### %s\n\n", title)
+			for _, item := range items {
+				out.WriteString("- " + item + "\n")
+			}
+		}
+		section("Added classes", mapItems(p.AddedClasses, func(s string) string { return "`" + s + "`" }))
+		section("Removed classes", mapItems(p.RemovedClasses, func(s string) string { return "`" + s + "`" }))
+		section("Renamed classes", mapItems(p.RenamedClasses, func(r Rename) string { return "`" + r.From + "` → `" + r.To + "`" }))
+		section("Added methods", mapItems(p.AddedMethods, MemberRef.markdown))
+		section("Removed methods", mapItems(p.RemovedMethods, MemberRef.markdown))
+		section("Renamed methods", mapItems(p.RenamedMethods, MemberRename.markdown))
+		section("Changed descriptors", mapItems(p.ChangedDescriptors, func(d DescriptorChange) string {
+			return "`" + d.Class + "#" + d.Name + "`: `" + d.From + "` → `" + d.To + "`"
+		}))
+		section("Added fields", mapItems(p.AddedFields, MemberRef.markdown))
+		section("Removed fields", mapItems(p.RemovedFields, MemberRef.markdown))
+		section("Renamed fields", mapItems(p.RenamedFields, MemberRename.markdown))
+	}
+	return out.String()
+}
+
+func (m MemberRef) markdown() string {
+	return "`" + m.Class + "#" + m.Name + "` `" + m.Descriptor + "`"
+}
+
+func (r MemberRename) markdown() string {
+	return "`" + r.Class + "#" + r.From + "` → `" + r.To + "` `" + r.Descriptor + "`"
+}
+
+func mapItems[T any](items []T, f func(T) string) []string {
+	result := make([]string, len(items))
+	for i, item := range items {
+		result[i] = f(item)
+	}
+	return result
+}
@@ -0,0 +1,161 @@
+package java
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Hierarchy 记录 notch 命名空间下的继承关系，类名均为点分全名
+type Hierarchy struct {
+	Parents    map[string][]string // 父类在前，接口在后
+	Interfaces map[string]struct{} // 本身是接口的类
+
+	children     map[string][]string
+	childrenOnce sync.Once
+}
+
+// TypeHierarchy 某个类的所有父类型和子类型
+type TypeHierarchy struct {
+	Class            string   `json:"class"`
+	Interface        bool     `json:"interface"`
+	Superclasses     []string `json:"superclasses"`     // 由近到远，接口为空
+	Interfaces       []string `json:"interfaces"`       // 直接和间接实现或继承的接口
+	DirectSubclasses []string `json:"directSubclasses"` // 直接继承或实现该类型的类和接口
+	Subclasses       []string `json:"subclasses"`       // 直接和间接的所有子类型
+	Implementors     []string `json:"implementors"`     // 仅接口，直接或间接实现该接口的非接口类
+}
+
+func NewHierarchy() *Hierarchy {
+	return &Hierarchy{Parents: make(map[string][]string), Interfaces: make(map[string]struct{})}
+}
+
+func (h *Hierarchy) Add(class, superClass string, interfaces []string, isInterface bool) {
+	class = NormalizeClassName(class)
+	if isInterface {
+		h.Interfaces[class] = struct{}{}
+	}
+	parents := make([]string, 0, len(interfaces)+1)
+	if superClass != "" {
+		parents = append(parents, NormalizeClassName(superClass))
+	}
+	for _, i := range interfaces {
+		parents = append(parents, NormalizeClassName(i))
+	}
+	h.Parents[class] = parents
+}
+
+// Ancestors 按广度优先返回类本身及其所有父类和接口
+func (h *Hierarchy) Ancestors(class string) []string {
+	class = NormalizeClassName(class)
+	result := []string{class}
+	seen := map[string]struct{}{class: {}}
+	for i := 0; i < len(result); i++ {
+		for _, parent := range h.Parents[result[i]] {
+			if _, ok := seen[parent]; !ok {
+				seen[parent] = struct{}{}
+				result = append(result, parent)
+			}
+		}
+	}
+	return result
+}
+
+// TypeHierarchy 计算类的父类链、接口和子类型，类不在 jar 中时返回 false
+func (h *Hierarchy) TypeHierarchy(class string) (TypeHierarchy, bool) {
+	class = NormalizeClassName(class)
+	parents, ok := h.Parents[class]
+	if !ok {
+		return TypeHierarchy{}, false
+	}
+	_, isInterface := h.Interfaces[class]
+	result := TypeHierarchy{Class: class, Interface: isInterface}
+	// 沿父类链收集，接口的父类 Object 不计入
+	chain := []string{class}
+	for current := parents; !isInterface && len(current) > 0; current = h.Parents[current[0]] {
+		result.Superclasses = append(result.Superclasses, current[0])
+		chain = append(chain, current[0])
+	}
+	seen := make(map[string]struct{})
+	queue := make([]string, 0)
+	for _, c := range chain {
+		if ps := h.Parents[c]; len(ps) > 1 {
+			queue = append(queue, ps[1:]...)
+		}
+	}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		if _, ok := seen[current]; ok {
+			continue
+		}
+		seen[current] = struct{}{}
+		result.Interfaces = append(result.Interfaces, current)
+		if ps := h.Parents[current]; len(ps) > 1 {
+			queue = append(queue, ps[1:]...)
+		}
+	}
+
+	children := h.childIndex()
+	result.DirectSubclasses = append(result.DirectSubclasses, children[class]...)
+	visited := map[string]struct{}{class: {}}
+	queue = append(queue, children[class]...)
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		if _, ok := visited[current]; ok {
+			continue
+		}
+		visited[current] = struct{}{}
+		result.Subclasses = append(result.Subclasses, current)
+		if _, ok := h.Interfaces[current]; isInterface && !ok {
+			result.Implementors = append(result.Implementors, current)
+		}
+		queue = append(queue, children[current]...)
+	}
+	result.sort()
+	return result, true
+}
+
+// 子类型索引只在第一次查询时构建
+func (h *Hierarchy) childIndex() map[string][]string {
+	h.childrenOnce.Do(func() {
+		h.children = make(map[string][]string)
+		for class, parents := range h.Parents {
+			for _, parent := range parents {
+				h.children[parent] = append(h.children[parent], class)
+			}
+		}
+	})
+	return h.children
+}
+
+// Rename 将所有类名转换到其它命名空间
+func (t *TypeHierarchy) Rename(rename func(class string) string) {
+	t.Class = rename(t.Class)
+	for _, list := range [][]string{t.Superclasses, t.Interfaces, t.DirectSubclasses, t.Subclasses, t.Implementors} {
+		for i := range list {
+			list[i] = rename(list[i])
+		}
+	}
+	t.sort()
+}
+
+// 父类链保持顺序，其余按名称排序，空列表输出为 []
+func (t *TypeHierarchy) sort() {
+	for _, list := range []*[]string{&t.Superclasses, &t.Interfaces, &t.DirectSubclasses, &t.Subclasses, &t.Implementors} {
+		if *list == nil {
+			*list = []string{}
+		} else if list != &t.Superclasses {
+			sort.Strings(*list)
+		}
+	}
+}
+
+// NormalizeClassName 将内部名称或类型签名统一为点分全名
+func NormalizeClassName(name string) string {
+	if strings.HasPrefix(name, "L") && strings.HasSuffix(name, ";") {
+		name = name[1 : len(name)-1]
+	}
+	return strings.ReplaceAll(name, "/", ".")
+}
@@ -10,6 +10,7 @@ type Mappings struct {
 	NamedToNotch map[SingleInfo]SingleInfo
 	NotchByName  map[string][]SingleInfo
 	NamedByName  map[string][]SingleInfo
+	suggestIndex []suggestEntry
 }
 
 type InfoForNetwork struct {
@@ -29,6 +30,22 @@ type searchResult struct {
 }
 
 func (m *Mappings) Search(keyword string, maxCount int) []InfoForNetwork {
+	return m.search(keyword, nil, maxCount)
+}
+
+// SearchInClass 只返回指定类及其父类、接口中声明的成员
+func (m *Mappings) SearchInClass(keyword string, class SingleInfo, hierarchy *Hierarchy, maxCount int) []InfoForNetwork {
+	classes := make(map[string]struct{})
+	for _, ancestor := range hierarchy.Ancestors(class.Class) {
+		classes[ancestor] = struct{}{}
+	}
+	return m.search(keyword, func(notch SingleInfo) bool {
+		_, ok := classes[NormalizeClassName(notch.Class)]
+		return ok
+	}, maxCount)
+}
+
+func (m *Mappings) search(keyword string, filter func(notch SingleInfo) bool, maxCount int) []InfoForNetwork {
 	if maxCount <= 0 {
 		return []InfoForNetwork{}
 	}
@@ -44,6 +61,9 @@ func (m *Mappings) Search(keyword string, maxCount int) []InfoForNetwork {
 		}
 		matchType := getMatchType(name, keyword)
 		for _, notch := range infos {
+			if filter != nil && !filter(notch) {
+				continue
+			}
 			// 直接从NotchToNamed获取对应的Named
 			if named, exists := m.NotchToNamed[notch]; exists {
 				key := notch.Name + "|" + named.Name
@@ -72,6 +92,9 @@ func (m *Mappings) Search(keyword string, maxCount int) []InfoForNetwork {
 		for _, named := range infos {
 			// 直接从NamedToNotch获取对应的Notch
 			if notch, exists := m.NamedToNotch[named]; exists {
+				if filter != nil && !filter(notch) {
+					continue
+				}
 				key := notch.Name + "|" + named.Name
 				if _, ok := seen[key]; !ok {
 					seen[key] = struct{}{}
@@ -122,10 +145,91 @@ func (m *Mappings) Search(keyword string, maxCount int) []InfoForNetwork {
 	return final
 }
 
-func (m *Mappings) AppendTranslate(infos *[]InfoForNetwork) {
+// AppendTranslate 填充目标映射中的名称，hierarchy 不为空时会沿继承链查找未重复声明的成员
+func (m *Mappings) AppendTranslate(infos *[]InfoForNetwork, hierarchy *Hierarchy) {
 	for i, info := range *infos {
-		(*infos)[i].Translated = m.NotchToNamed[info.Notch]
+		(*infos)[i].Translated, _ = m.ResolveMember(info.Notch, hierarchy)
+	}
+}
+
+// ResolveMember 查找 notch 成员对应的命名，找不到时依次尝试父类和接口
+func (m *Mappings) ResolveMember(notch SingleInfo, hierarchy *Hierarchy) (SingleInfo, bool) {
+	if named, ok := m.NotchToNamed[notch]; ok || hierarchy == nil || notch.Type == "class" {
+		return named, ok
+	}
+	for _, ancestor := range hierarchy.Ancestors(notch.Class) {
+		notch.Class = ancestor
+		if named, ok := m.NotchToNamed[notch]; ok {
+			return named, true
+		}
+	}
+	return SingleInfo{}, false
+}
+
+// FindMember 按名称在 notch 类中查找成员，命名和 notch 名称均可，找不到时依次尝试父类和接口，
+// 返回最近一个声明了该名称的类中的所有重载，descriptor 不为空时只返回描述符相同的成员
+func (m *Mappings) FindMember(class SingleInfo, name, descriptor string, hierarchy *Hierarchy) []InfoForNetwork {
+	candidates := make([]InfoForNetwork, 0)
+	for _, named := range m.NamedByName[name] {
+		if notch, ok := m.NamedToNotch[named]; ok {
+			candidates = append(candidates, InfoForNetwork{Notch: notch, Named: named})
+		}
+	}
+	for _, notch := range m.NotchByName[name] {
+		if named, ok := m.NotchToNamed[notch]; ok {
+			candidates = append(candidates, InfoForNetwork{Notch: notch, Named: named})
+		}
+	}
+	for _, ancestor := range hierarchy.Ancestors(class.Class) {
+		results := make([]InfoForNetwork, 0)
+		seen := make(map[SingleInfo]struct{})
+		for _, info := range candidates {
+			if info.Notch.Type == "class" || NormalizeClassName(info.Notch.Class) != ancestor {
+				continue
+			}
+			if descriptor != "" && info.Named.Signature != descriptor && info.Notch.Signature != descriptor {
+				continue
+			}
+			if _, ok := seen[info.Notch]; !ok {
+				seen[info.Notch] = struct{}{}
+				results = append(results, info)
+			}
+		}
+		if len(results) > 0 {
+			sort.Slice(results, func(a, b int) bool {
+				return results[a].Named.Signature < results[b].Named.Signature
+			})
+			return results
+		}
+	}
+	return []InfoForNetwork{}
+}
+
+// FindClass 按全名或简单名查找类，命名和 notch 名称均可，返回 notch 类信息
+func (m *Mappings) FindClass(name string) (SingleInfo, bool) {
+	name = NormalizeClassName(name)
+	simple := FullToClassName(name)
+	for _, named := range m.NamedByName[simple] {
+		if named.Type == "class" && (named.Class == name || simple == name) {
+			if notch, ok := m.NamedToNotch[named]; ok {
+				return notch, true
+			}
+		}
+	}
+	for _, notch := range m.NotchByName[simple] {
+		if notch.Type == "class" && notch.Class == name {
+			return notch, true
+		}
+	}
+	return SingleInfo{}, false
+}
+
+// ClassName 返回 notch 类在当前映射中的全名，没有映射的类（如 JDK 中的类）原样返回
+func (m *Mappings) ClassName(notch string) string {
+	if named, ok := m.NotchToNamed[PackClassInfo(notch)]; ok {
+		return named.Class
 	}
+	return notch
 }
 
 // 判断匹配类型并返回权重
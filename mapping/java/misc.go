@@ -50,5 +50,6 @@ func BuildMapping(mapping *map[SingleInfo]SingleInfo) *Mappings {
 		result.NotchByName[k.Name] = append(result.NotchByName[k.Name], k)
 		result.NamedByName[v.Name] = append(result.NamedByName[v.Name], v)
 	}
+	result.suggestIndex = buildSuggestIndex(result.NamedByName)
 	return &result
 }
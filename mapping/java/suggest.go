@@ -0,0 +1,77 @@
+package java
+
+import (
+	"sort"
+	"strings"
+)
+
+type Suggestion struct {
+	Name  string `json:"name"`
+	Type  string `json:"type"`
+	Count int    `json:"count"`
+}
+
+type suggestEntry struct {
+	key        string // 小写名称，用于前缀查找
+	suggestion Suggestion
+}
+
+// 按名称和类型聚合，次数即为该名称的流行度
+func buildSuggestIndex(namedByName map[string][]SingleInfo) []suggestEntry {
+	counts := make(map[Suggestion]int)
+	for name, infos := range namedByName {
+		for _, info := range infos {
+			counts[Suggestion{Name: name, Type: info.Type}]++
+		}
+	}
+	index := make([]suggestEntry, 0, len(counts))
+	for s, count := range counts {
+		s.Count = count
+		index = append(index, suggestEntry{key: strings.ToLower(s.Name), suggestion: s})
+	}
+	sort.Slice(index, func(i, j int) bool {
+		if index[i].key != index[j].key {
+			return index[i].key < index[j].key
+		}
+		return index[i].suggestion.Type < index[j].suggestion.Type
+	})
+	return index
+}
+
+func (m *Mappings) Suggest(prefix string, maxCount int) []Suggestion {
+	if maxCount <= 0 || prefix == "" {
+		return []Suggestion{}
+	}
+	prefix = strings.ToLower(prefix)
+	start := sort.Search(len(m.suggestIndex), func(i int) bool {
+		return m.suggestIndex[i].key >= prefix
+	})
+	end := start
+	for end < len(m.suggestIndex) && strings.HasPrefix(m.suggestIndex[end].key, prefix) {
+		end++
+	}
+
+	results := make([]Suggestion, 0, end-start)
+	for _, entry := range m.suggestIndex[start:end] {
+		results = append(results, entry.suggestion)
+	}
+	// 完全匹配优先，其次按流行度、类型权重和名称排序
+	sort.Slice(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+		aExact, bExact := strings.ToLower(a.Name) == prefix, strings.ToLower(b.Name) == prefix
+		if aExact != bExact {
+			return aExact
+		}
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		if getTypeWeight(a.Type) != getTypeWeight(b.Type) {
+			return getTypeWeight(a.Type) > getTypeWeight(b.Type)
+		}
+		return a.Name < b.Name
+	})
+	if len(results) > maxCount {
+		results = results[:maxCount]
+	}
+	return results
+}
@@ -0,0 +1,179 @@
+package java
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+type TokenKind int
+
+const (
+	TokenWhitespace TokenKind = iota
+	TokenComment
+	TokenKeyword
+	TokenIdentifier
+	TokenString // 字符串、字符和文本块
+	TokenNumber
+	TokenOperator // 运算符和分隔符
+)
+
+// Token 词法单元，所有 Token 的 Text 依次拼接即为原文
+type Token struct {
+	Kind   TokenKind
+	Text   string
+	Line   int // 从 1 开始
+	Offset int // 字节偏移
+}
+
+var keywords = map[string]struct{}{}
+
+func init() {
+	for _, k := range strings.Fields(`abstract assert boolean break byte case catch char class const continue default do
+		double else enum extends final finally float for goto if implements import instanceof int interface long native
+		new package private protected public return short static strictfp super switch synchronized this throw throws
+		transient try void volatile while true false null var record yield sealed permits non-sealed`) {
+		keywords[k] = struct{}{}
+	}
+}
+
+func IsKeyword(s string) bool {
+	_, ok := keywords[s]
+	return ok
+}
+
+// Tokenize 对 Java 源码进行词法分析，无法识别的字符作为单字符运算符处理
+func Tokenize(src string) []Token {
+	tokens := make([]Token, 0, len(src)/4)
+	line := 1
+	for i := 0; i < len(src); {
+		start := i
+		kind := TokenOperator
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f':
+			kind = TokenWhitespace
+			for i < len(src) && strings.IndexByte(" \t\n\r\f", src[i]) >= 0 {
+				i++
+			}
+		case strings.HasPrefix(src[i:], "//"):
+			kind = TokenComment
+			i = indexFrom(src, i, "\n")
+		case strings.HasPrefix(src[i:], "/*"):
+			kind = TokenComment
+			i = indexFrom(src, i+2, "*/")
+			if i < len(src) {
+				i += 2
+			}
+		case strings.HasPrefix(src[i:], `"""`):
+			kind = TokenString
+			i = skipQuoted(src, i+3, `"""`)
+		case c == '"' || c == '\'':
+			kind = TokenString
+			i = skipQuoted(src, i+1, string(c))
+		case c >= '0' && c <= '9' || c == '.' && i+1 < len(src) && src[i+1] >= '0' && src[i+1] <= '9':
+			kind = TokenNumber
+			i = skipNumber(src, i)
+		case isIdentifierStart(src, i):
+			kind = TokenIdentifier
+			for i < len(src) && isIdentifierPart(src, i) {
+				_, size := utf8.DecodeRuneInString(src[i:])
+				i += size
+			}
+			if word := src[start:i]; IsKeyword(word) {
+				kind = TokenKeyword
+			} else if word == "non" && strings.HasPrefix(src[i:], "-sealed") {
+				i += len("-sealed")
+				kind = TokenKeyword
+			}
+		default:
+			i += operatorLength(src[i:])
+		}
+		if i > len(src) {
+			i = len(src)
+		}
+		text := src[start:i]
+		tokens = append(tokens, Token{Kind: kind, Text: text, Line: line, Offset: start})
+		line += strings.Count(text, "\n")
+	}
+	return tokens
+}
+
+// 返回 sep 在 from 之后的位置，找不到时返回末尾
+func indexFrom(src string, from int, sep string) int {
+	if from > len(src) {
+		return len(src)
+	}
+	if idx := strings.Index(src[from:], sep); idx >= 0 {
+		return from + idx
+	}
+	return len(src)
+}
+
+func skipQuoted(src string, i int, quote string) int {
+	for i < len(src) {
+		if src[i] == '\\' {
+			i += 2
+			continue
+		}
+		if strings.HasPrefix(src[i:], quote) {
+			return i + len(quote)
+		}
+		// 普通字符串不会跨行，遇到换行说明源码有误，及时止损
+		if src[i] == '\n' && len(quote) == 1 {
+			return i
+		}
+		i++
+	}
+	return len(src)
+}
+
+func skipNumber(src string, i int) int {
+	start := i
+	hex := len(src) > i+1 && src[i] == '0' && (src[i+1] == 'x' || src[i+1] == 'X')
+	for i < len(src) {
+		c := src[i]
+		if isAsciiAlnum(c) || c == '_' || c == '.' {
+			i++
+			continue
+		}
+		// 指数符号：十进制为 e，十六进制浮点为 p
+		if (c == '+' || c == '-') && i > start {
+			prev := src[i-1] | 0x20
+			if !hex && prev == 'e' || hex && prev == 'p' {
+				i++
+				continue
+			}
+		}
+		break
+	}
+	return i
+}
+
+func isAsciiAlnum(c byte) bool {
+	return c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func isIdentifierStart(src string, i int) bool {
+	r, _ := utf8.DecodeRuneInString(src[i:])
+	return r == '_' || r == '$' || unicode.IsLetter(r)
+}
+
+func isIdentifierPart(src string, i int) bool {
+	r, _ := utf8.DecodeRuneInString(src[i:])
+	return r == '_' || r == '$' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+var operators = []string{"<<=", "...", "->", "::", "++", "--", "&&", "||", "==", "!=", "<=", ">=",
+	"+=", "-=", "*=", "/=", "&=", "|=", "^=", "%=", "<<"}
+
+// 泛型中的 >> 需要拆开，因此右移运算符按单字符处理
+func operatorLength(s string) int {
+	for _, op := range operators {
+		if strings.HasPrefix(s, op) {
+			return len(op)
+		}
+	}
+	_, size := utf8.DecodeRuneInString(s)
+	return size
+}
@@ -0,0 +1,479 @@
+package java
+
+import "strings"
+
+// TranslateSource 将基于 from 映射编写的 Java 代码中的类、方法和字段名改写为 to 映射中的名称，
+// 类名通过 import、包声明和全限定名确定，成员优先在所属类及其父类中查找，
+// 接收者类型未知时只在名称能唯一翻译时改写。hierarchy 为 notch 命名空间的继承关系，可以为 nil
+func TranslateSource(src string, from, to *Mappings, hierarchy *Hierarchy) string {
+	t := &translator{
+		from:      from,
+		to:        to,
+		hierarchy: hierarchy,
+		tokens:    Tokenize(src),
+		imports:   make(map[string]SingleInfo),
+		replace:   make(map[int]string),
+	}
+	for i, token := range t.tokens {
+		if token.Kind != TokenWhitespace && token.Kind != TokenComment {
+			t.significant = append(t.significant, i)
+		}
+	}
+	t.readHeader()
+	t.readSupertypes()
+	for i := 0; i < len(t.significant); {
+		i = t.translateAt(i)
+	}
+	var out strings.Builder
+	out.Grow(len(src))
+	for i, token := range t.tokens {
+		if text, ok := t.replace[i]; ok {
+			out.WriteString(text)
+		} else {
+			out.WriteString(token.Text)
+		}
+	}
+	return out.String()
+}
+
+type translator struct {
+	from, to    *Mappings
+	hierarchy   *Hierarchy
+	tokens      []Token
+	significant []int // 非空白和注释的 Token 下标
+	pkg         string
+	imports     map[string]SingleInfo // 简单名 -> from 中的类
+	wildcards   []string              // 通配导入的包或外部类
+	owners      []SingleInfo          // 代码中声明的类继承的父类型，用于解析未限定的成员
+	replace     map[int]string        // Token 下标 -> 新文本，空串表示删除
+}
+
+func (t *translator) text(i int) string {
+	if i < 0 || i >= len(t.significant) {
+		return ""
+	}
+	return t.tokens[t.significant[i]].Text
+}
+
+func (t *translator) isIdentifier(i int) bool {
+	return i >= 0 && i < len(t.significant) && t.tokens[t.significant[i]].Kind == TokenIdentifier
+}
+
+// 读取 a.b.C 形式的名称，返回各段及其后的位置
+func (t *translator) readChain(i int) ([]string, int) {
+	var parts []string
+	for t.isIdentifier(i) {
+		parts = append(parts, t.text(i))
+		if t.text(i+1) != "." || !t.isIdentifier(i+2) {
+			return parts, i + 1
+		}
+		i += 2
+	}
+	return parts, i
+}
+
+// 解析 package 和 import 声明
+func (t *translator) readHeader() {
+	for i := 0; i < len(t.significant); i++ {
+		switch t.text(i) {
+		case "package":
+			parts, _ := t.readChain(i + 1)
+			t.pkg = strings.Join(parts, ".")
+		case "import":
+			j := i + 1
+			static := t.text(j) == "static"
+			if static {
+				j++
+			}
+			parts, next := t.readChain(j)
+			if len(parts) == 0 {
+				continue
+			}
+			if t.text(next) == "." && t.text(next+1) == "*" {
+				if class, ok := t.fullClass(parts); ok {
+					t.wildcards = append(t.wildcards, class.Class+"$")
+				} else {
+					t.wildcards = append(t.wildcards, strings.Join(parts, ".")+".")
+				}
+				continue
+			}
+			if static {
+				continue
+			}
+			if class, ok := t.fullClass(parts); ok {
+				t.imports[parts[len(parts)-1]] = class
+			}
+		}
+	}
+}
+
+// 解析代码中类声明的 extends 和 implements
+func (t *translator) readSupertypes() {
+	for i := 0; i < len(t.significant); i++ {
+		if t.text(i) != "extends" && t.text(i) != "implements" {
+			continue
+		}
+		for j := i + 1; j < len(t.significant) && t.text(j) != "{" && t.text(j) != ";"; j++ {
+			if t.text(j) == "<" {
+				// 跳过泛型参数
+				for depth := 0; j < len(t.significant); j++ {
+					if t.text(j) == "<" {
+						depth++
+					} else if t.text(j) == ">" {
+						if depth--; depth == 0 {
+							break
+						}
+					}
+				}
+				continue
+			}
+			if !t.isIdentifier(j) {
+				continue
+			}
+			parts, next := t.readChain(j)
+			if class, ok := t.fullClass(parts); ok {
+				t.owners = append(t.owners, class)
+			} else if class, ok := t.simpleClass(parts[0]); ok && len(parts) == 1 {
+				t.owners = append(t.owners, class)
+			}
+			j = next - 1
+		}
+	}
+}
+
+// fullClass 将全限定名解析为 from 中的类，内部类可以用 . 或 $ 分隔
+func (t *translator) fullClass(parts []string) (SingleInfo, bool) {
+	if len(parts) < 2 {
+		return SingleInfo{}, false
+	}
+	name := strings.Join(parts, ".")
+	for {
+		if class, ok := t.findNamedClass(name); ok {
+			return class, true
+		}
+		idx := strings.LastIndexByte(name, '.')
+		if idx < 0 {
+			return SingleInfo{}, false
+		}
+		name = name[:idx] + "$" + name[idx+1:]
+	}
+}
+
+func (t *translator) findNamedClass(full string) (SingleInfo, bool) {
+	for _, named := range t.from.NamedByName[FullToClassName(full)] {
+		if named.Type == "class" && named.Class == full {
+			return named, true
+		}
+	}
+	return SingleInfo{}, false
+}
+
+// simpleClass 依次通过 import、当前包、通配导入和全局唯一的简单名解析类
+func (t *translator) simpleClass(simple string) (SingleInfo, bool) {
+	if class, ok := t.imports[simple]; ok {
+		return class, true
+	}
+	if t.pkg != "" {
+		if class, ok := t.findNamedClass(t.pkg + "." + simple); ok {
+			return class, true
+		}
+	}
+	for _, prefix := range t.wildcards {
+		if class, ok := t.findNamedClass(prefix + simple); ok {
+			return class, true
+		}
+	}
+	var found []SingleInfo
+	for _, named := range t.from.NamedByName[simple] {
+		if named.Type == "class" {
+			found = append(found, named)
+		}
+	}
+	if len(found) == 1 {
+		return found[0], true
+	}
+	return SingleInfo{}, false
+}
+
+// 将类名翻译为 to 中的名称，full 为 true 时输出全限定名，innerOnly 为 true 时只输出内部类名
+func (t *translator) className(class SingleInfo, full, innerOnly bool) (string, bool) {
+	notch, ok := t.from.NamedToNotch[class]
+	if !ok {
+		return "", false
+	}
+	named, ok := t.to.NotchToNamed[notch]
+	if !ok {
+		return "", false
+	}
+	name := named.Class
+	if !full {
+		name = name[strings.LastIndexByte(name, '.')+1:]
+	}
+	if innerOnly {
+		name = name[strings.LastIndexByte(name, '$')+1:]
+	}
+	return strings.ReplaceAll(name, "$", "."), true
+}
+
+// translateAt 从第 i 个有效 Token 开始翻译一个名称链，返回下一个位置
+func (t *translator) translateAt(i int) int {
+	if !t.isIdentifier(i) {
+		return i + 1
+	}
+	prev := t.text(i - 1)
+	parts, next := t.readChain(i)
+	var owner *SingleInfo
+	start := 0
+	switch {
+	case prev == "." && (t.text(i-2) == "this" || t.text(i-2) == "super"):
+		t.translateMembers(i, parts, t.owners)
+		return next
+	case prev == "." || prev == "::":
+		// 接收者类型未知
+		t.translateMembers(i, parts, nil)
+		return next
+	case prev == "package":
+		return next
+	}
+	// 最长的全限定类名前缀
+	for k := len(parts); k >= 2; k-- {
+		if class, ok := t.fullClass(parts[:k]); ok {
+			if name, ok := t.className(class, true, false); ok {
+				t.replaceRange(i, i+2*(k-1), name)
+			}
+			owner, start = &class, k
+			break
+		}
+	}
+	if owner == nil && !t.isDeclaredName(i) {
+		if class, ok := t.simpleClass(parts[0]); ok {
+			if name, ok := t.className(class, false, strings.Contains(class.Name, "$") && !strings.Contains(parts[0], "$")); ok {
+				t.replaceRange(i, i, name)
+			}
+			owner, start = &class, 1
+		}
+	}
+	if owner != nil {
+		t.translateMembers(i+2*start, parts[start:], []SingleInfo{*owner})
+		return next
+	}
+	if prev == "import" || prev == "static" {
+		// 通配导入的包
+		if t.text(next) == "." && t.text(next+1) == "*" {
+			if pkg, ok := t.packageName(strings.Join(parts, ".")); ok {
+				t.replaceRange(i, next-1, pkg)
+			}
+		}
+		return next
+	}
+	// 未限定的名称只在声明的父类型中查找，找不到时可能是局部变量，只有方法调用才按名称全局查找
+	var owners []SingleInfo
+	switch {
+	case len(t.owners) > 0 && t.translateMember(i, t.owners, false):
+		owners = t.memberType(i, t.owners)
+	case t.text(i+1) == "(":
+		t.translateMember(i, nil, true)
+	}
+	t.translateMembers(i+2, parts[1:], owners)
+	return next
+}
+
+// packageName 在包中所有类都被映射到同一个包时返回新包名
+func (t *translator) packageName(pkg string) (string, bool) {
+	result := ""
+	for _, infos := range t.from.NamedByName {
+		for _, named := range infos {
+			if named.Type != "class" || !strings.HasPrefix(named.Class, pkg+".") || strings.Contains(named.Class[len(pkg)+1:], ".") {
+				continue
+			}
+			notch, ok := t.from.NamedToNotch[named]
+			if !ok {
+				continue
+			}
+			translated, ok := t.to.NotchToNamed[notch]
+			if !ok {
+				continue
+			}
+			target := translated.Class[:max(strings.LastIndexByte(translated.Class, '.'), 0)]
+			if result != "" && result != target {
+				return "", false
+			}
+			result = target
+		}
+	}
+	return result, result != ""
+}
+
+// 紧跟在 class、interface 等关键字后的是代码自己声明的类型
+func (t *translator) isDeclaredName(i int) bool {
+	switch t.text(i - 1) {
+	case "class", "interface", "enum", "record":
+		return true
+	}
+	return false
+}
+
+// translateMembers 依次翻译名称链中的成员，owners 为空时按名称全局查找
+func (t *translator) translateMembers(i int, parts []string, owners []SingleInfo) {
+	for k := range parts {
+		pos := i + 2*k
+		if len(owners) == 1 {
+			// 外部类.内部类
+			if inner, ok := t.findNamedClass(owners[0].Class + "$" + parts[k]); ok {
+				if name, ok := t.className(inner, false, true); ok {
+					t.replaceRange(pos, pos, name)
+				}
+				owners = []SingleInfo{inner}
+				continue
+			}
+		}
+		if len(owners) > 0 {
+			if t.translateMember(pos, owners, false) {
+				owners = t.memberType(pos, owners)
+				continue
+			}
+		}
+		t.translateMember(pos, nil, true)
+		owners = nil
+	}
+}
+
+// translateMember 翻译第 i 个有效 Token 上的成员名，owners 为空时只有名称能唯一翻译才改写
+func (t *translator) translateMember(i int, owners []SingleInfo, global bool) bool {
+	name := t.text(i)
+	kind := "field"
+	if t.text(i+1) == "(" {
+		kind = "method"
+	}
+	var candidates []SingleInfo
+	if owners != nil {
+		candidates = t.membersOf(name, kind, owners)
+		if len(candidates) == 0 && kind == "field" && t.text(i+1) == ";" {
+			// import static 导入的可能是方法
+			candidates = t.membersOf(name, "method", owners)
+		}
+	} else if global {
+		for _, named := range t.from.NamedByName[name] {
+			if named.Type == kind {
+				candidates = append(candidates, named)
+			}
+		}
+	}
+	target := ""
+	for _, candidate := range candidates {
+		notch, ok := t.from.NamedToNotch[candidate]
+		if !ok {
+			continue
+		}
+		translated, ok := t.resolveMember(notch)
+		if !ok {
+			continue
+		}
+		if target != "" && target != translated.Name {
+			// 重名成员翻译结果不一致，无法确定
+			return false
+		}
+		target = translated.Name
+	}
+	if target == "" {
+		return false
+	}
+	if target != name {
+		t.replaceRange(i, i, target)
+	}
+	return true
+}
+
+// 在 to 中查找 notch 成员，不同映射的 notch 描述符可能缺失，因此找不到时按所属类和名称匹配
+func (t *translator) resolveMember(notch SingleInfo) (SingleInfo, bool) {
+	if named, ok := t.to.ResolveMember(notch, t.hierarchy); ok {
+		return named, true
+	}
+	classes := []string{notch.Class}
+	if t.hierarchy != nil {
+		classes = t.hierarchy.Ancestors(notch.Class)
+	}
+	for _, class := range classes {
+		var result SingleInfo
+		found := false
+		for _, candidate := range t.to.NotchByName[notch.Name] {
+			if candidate.Type != notch.Type || candidate.Class != class ||
+				(notch.Signature != "" && candidate.Signature != "" && candidate.Signature != notch.Signature) {
+				continue
+			}
+			named, ok := t.to.NotchToNamed[candidate]
+			if !ok {
+				continue
+			}
+			if found && named.Name != result.Name {
+				return SingleInfo{}, false
+			}
+			result, found = named, true
+		}
+		if found {
+			return result, true
+		}
+	}
+	return SingleInfo{}, false
+}
+
+// 在 owners 及其父类中查找成员，找到最近的一层即返回
+func (t *translator) membersOf(name, kind string, owners []SingleInfo) []SingleInfo {
+	for _, owner := range owners {
+		classes := []string{owner.Class}
+		if notch, ok := t.from.NamedToNotch[owner]; ok && t.hierarchy != nil {
+			classes = classes[:0]
+			for _, ancestor := range t.hierarchy.Ancestors(notch.Class) {
+				classes = append(classes, t.from.ClassName(ancestor))
+			}
+		}
+		for _, class := range classes {
+			var result []SingleInfo
+			for _, named := range t.from.NamedByName[name] {
+				if named.Type == kind && named.Class == class {
+					result = append(result, named)
+				}
+			}
+			if len(result) > 0 {
+				return result
+			}
+		}
+	}
+	return nil
+}
+
+// 字段的类型作为后续成员的所属类，方法调用之后类型未知
+func (t *translator) memberType(i int, owners []SingleInfo) []SingleInfo {
+	if t.text(i+1) == "(" {
+		return nil
+	}
+	fields := t.membersOf(t.text(i), "field", owners)
+	if len(fields) != 1 {
+		return nil
+	}
+	signature := fields[0].Signature
+	if signature == "" {
+		// 部分映射只有 notch 描述符
+		notch := t.from.NamedToNotch[fields[0]]
+		if !strings.HasPrefix(notch.Signature, "L") {
+			return nil
+		}
+		signature = "L" + t.from.ClassName(NormalizeClassName(notch.Signature)) + ";"
+	}
+	if !strings.HasPrefix(signature, "L") {
+		return nil
+	}
+	if class, ok := t.findNamedClass(NormalizeClassName(signature)); ok {
+		return []SingleInfo{class}
+	}
+	return nil
+}
+
+// 用 text 替换第 from 到第 to 个有效 Token（含）之间的所有内容
+func (t *translator) replaceRange(from, to int, text string) {
+	start, end := t.significant[from], t.significant[to]
+	t.replace[start] = text
+	for j := start + 1; j <= end; j++ {
+		t.replace[j] = ""
+	}
+}
@@ -0,0 +1,56 @@
+package java
+
+import (
+	"strings"
+	"testing"
+)
+
+func buildTestMappings(names map[string]string, members map[SingleInfo]SingleInfo) *Mappings {
+	m := map[SingleInfo]SingleInfo{}
+	for notch, named := range names {
+		m[SingleInfo{Name: notch, Class: notch, Type: "class"}] = PackClassInfo(named)
+	}
+	for notch, named := range members {
+		m[notch] = named
+	}
+	return BuildMapping(&m)
+}
+
+func TestTranslateMethodAfterClassReturnType(t *testing.T) {
+	yarn := buildTestMappings(map[string]string{
+		"a": "net.minecraft.block.Block",
+		"b": "net.minecraft.block.BlockState",
+	}, map[SingleInfo]SingleInfo{
+		{Name: "c", Class: "a", Signature: "(Lb;)Lb;", Type: "method"}: {Name: "getStateForNeighborUpdate", Class: "net.minecraft.block.Block", Signature: "(Lnet/minecraft/block/BlockState;)Lnet/minecraft/block/BlockState;", Type: "method"},
+		{Name: "d", Class: "a", Signature: "()V", Type: "method"}:      {Name: "tick", Class: "net.minecraft.block.Block", Signature: "()V", Type: "method"},
+	})
+	official := buildTestMappings(map[string]string{
+		"a": "net.minecraft.world.level.block.Block",
+		"b": "net.minecraft.world.level.block.state.BlockState",
+	}, map[SingleInfo]SingleInfo{
+		{Name: "c", Class: "a", Signature: "(Lb;)Lb;", Type: "method"}: {Name: "updateShape", Class: "net.minecraft.world.level.block.Block", Type: "method"},
+		{Name: "d", Class: "a", Signature: "()V", Type: "method"}:      {Name: "serverTick", Class: "net.minecraft.world.level.block.Block", Type: "method"},
+	})
+	src := `import net.minecraft.block.Block;
+import net.minecraft.block.BlockState;
+
+public class MyBlock extends Block {
+    public void tick() {}
+
+    public BlockState getStateForNeighborUpdate(BlockState s) {
+        return s;
+    }
+}
+`
+	got := TranslateSource(src, yarn, official, nil)
+	for _, want := range []string{
+		"import net.minecraft.world.level.block.Block;",
+		"import net.minecraft.world.level.block.state.BlockState;",
+		"public void serverTick()",
+		"public BlockState updateShape(BlockState s)",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("missing %q in:\n%s", want, got)
+		}
+	}
+}
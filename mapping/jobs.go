@@ -0,0 +1,137 @@
+package mapping
+
+import (
+	"context"
+	"errors"
+	"os"
+	"pluto/global"
+	"pluto/job"
+	"pluto/mapping/java"
+	"pluto/util"
+	"pluto/vanilla"
+)
+
+// 任务类型，也是线程池中限制同时运行数量的类别
+const (
+	JobDecompile = "decompile"
+	JobRemap     = "remap"
+	// 读取原版 jar 的继承关系，只需要下载，结果保存在内存中
+	JobHierarchy = "hierarchy"
+	// 单个类的反编译不记录为任务，只在线程池中以高优先级排队
+	TaskClass = "class"
+)
+
+// InitJobs 注册反编译、重映射和继承关系任务，并恢复上次未完成的任务
+func InitJobs() error {
+	job.Register(JobDecompile, job.Runner{
+		Run: func(ctx context.Context, key job.Key) error {
+			_, err := GenerateSource(ctx, key.Version, key.MappingType, key.Decompiler)
+			return err
+		},
+		Discard: discardPartial,
+		Retry:   global.Config.Worker.Retry[JobDecompile].RetryPolicy(),
+		Memory:  jobMemory,
+	})
+	job.Register(JobRemap, job.Runner{
+		Run: func(ctx context.Context, key job.Key) error {
+			_, err := RemapJar(ctx, key.Version, key.MappingType)
+			return err
+		},
+		Discard: discardPartial,
+		Retry:   global.Config.Worker.Retry[JobRemap].RetryPolicy(),
+		Memory:  jobMemory,
+	})
+	job.Register(JobHierarchy, job.Runner{
+		Run: func(ctx context.Context, key job.Key) error {
+			job.SetStage(ctx, job.StageDownload)
+			_, err := vanilla.LoadHierarchy(ctx, key.Version)
+			return err
+		},
+		Retry: global.Config.Worker.Retry[JobHierarchy].RetryPolicy(),
+	})
+	return job.Restore()
+}
+
+// 任务中依次运行重映射和反编译，按其中 -Xmx 最大的预留内存
+func jobMemory(key job.Key) int64 {
+	memory := util.ParseJavaMemory(global.Config.Remapper.JavaParams)
+	if key.Kind == JobDecompile {
+		memory = max(memory, decompilerMemory(key.Decompiler))
+	}
+	return memory
+}
+
+// SubmitDecompile 创建反编译任务，相同的任务正在进行时返回该任务
+func SubmitDecompile(mcVersion, mappingType, decompilerName string, priority util.Priority) (*job.Job, error) {
+	decompiler, err := GetDecompiler(decompilerName)
+	if err != nil {
+		return nil, err
+	}
+	if err := vanilla.CheckVersion(mcVersion); err != nil {
+		return nil, err
+	}
+	if _, ok := serviceMap[mappingType]; !ok {
+		return nil, errors.New("unknown mapping type")
+	}
+	return job.Submit(job.Key{Kind: JobDecompile, Version: mcVersion, MappingType: mappingType, Decompiler: decompiler.GetName()}, priority)
+}
+
+// SubmitRemap 创建单独生成重映射 jar 的任务，相同的任务正在进行时返回该任务
+func SubmitRemap(mcVersion, mappingType string, priority util.Priority) (*job.Job, error) {
+	if err := vanilla.CheckVersion(mcVersion); err != nil {
+		return nil, err
+	}
+	if _, ok := serviceMap[mappingType]; !ok {
+		return nil, errors.New("unknown mapping type")
+	}
+	return job.Submit(job.Key{Kind: JobRemap, Version: mcVersion, MappingType: mappingType}, priority)
+}
+
+// GetHierarchy 返回已加载的继承关系，未加载时创建在后台加载的任务并返回该任务
+func GetHierarchy(mcVersion string, priority util.Priority) (*java.Hierarchy, *job.Job, error) {
+	if hierarchy, ok := vanilla.CachedHierarchy(mcVersion); ok {
+		return hierarchy, nil, nil
+	}
+	if err := vanilla.CheckVersion(mcVersion); err != nil {
+		return nil, nil, err
+	}
+	j, err := job.Submit(job.Key{Kind: JobHierarchy, Version: mcVersion}, priority)
+	return nil, j, err
+}
+
+// RemapJar 单独生成重映射后的 jar，已存在时直接返回
+func RemapJar(ctx context.Context, mcVersion, mappingType string) (string, error) {
+	service, ok := serviceMap[mappingType]
+	if !ok {
+		return "", errors.New("unknown mapping type")
+	}
+	return ensureRemapped(ctx, service, mcVersion)
+}
+
+// 删除被中断的任务在对应阶段写入的文件，重映射中断时 jar 不完整，反编译或建立索引中断时源码不完整
+func discardPartial(key job.Key, stage job.Stage) error {
+	service, ok := serviceMap[key.MappingType]
+	if !ok {
+		return nil
+	}
+	switch stage {
+	case job.StageRemap:
+		if err := os.Remove(global.GetRemappedPath(service, key.Version)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	case job.StageDecompile, job.StageIndex:
+		if key.Kind != JobDecompile {
+			return nil
+		}
+		decompiler, err := GetDecompiler(key.Decompiler)
+		if err != nil {
+			return err
+		}
+		// 已经记录为可用时只是没来得及保存任务状态
+		if IsAvailable(key.Version, key.MappingType, decompiler.GetName()) {
+			return nil
+		}
+		return os.RemoveAll(global.GetSourceFolder(service, key.Version, decompiler.GetName()))
+	}
+	return nil
+}
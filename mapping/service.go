@@ -1,14 +1,28 @@
 package mapping
 
 import (
+	"archive/zip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"log/slog"
+	"os"
+	"path/filepath"
+	"pluto/classfile"
 	"pluto/global"
+	"pluto/job"
 	"pluto/mapping/java"
 	"pluto/mapping/services"
+	"pluto/source"
 	"pluto/util"
+	"pluto/vanilla"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 )
 
@@ -18,7 +32,7 @@ type Service interface {
 	GetMappingCacheOrError(mcVersion string) (*java.Mappings, error)
 	SaveMappingCache(mcVersion string, mapping *java.Mappings)
 	LoadMapping(mcVersion string) (*map[java.SingleInfo]java.SingleInfo, error) //All default is notch->target
-	Remap(mcVersion string) (string, error)
+	Remap(ctx context.Context, mcVersion string) (string, error)
 }
 
 var (
@@ -27,6 +41,14 @@ var (
 		"yarn":     &services.Yarn{},
 	}
 	loadMappingLock = util.NewNamedLock()
+
+	ErrClassNotFound = errors.New("cannot find class in remapped jar")
+	ErrRemapping     = errors.New("jar is being remapped")
+	// 重映射会覆盖 jar，读取 jar 时需要持有读锁
+	remapLocks     = map[string]*sync.RWMutex{}
+	remapLocksLock sync.Mutex
+	checksums      = map[string]checksumEntry{}
+	checksumsLock  sync.Mutex
 )
 
 func LoadMapping(mcVersion, mappingType string) (*java.Mappings, error) {
@@ -55,31 +77,236 @@ func LoadMapping(mcVersion, mappingType string) (*java.Mappings, error) {
 	return m3, nil
 }
 
-func GenerateSource(mcVersion, mappingType string) (string, error) {
+func GenerateSource(ctx context.Context, mcVersion, mappingType, decompilerName string) (string, error) {
 	start := time.Now()
-	if !CanAddTask(mcVersion, mappingType) {
-		return "", errors.New("this type has generated or generating")
+	decompiler, err := GetDecompiler(decompilerName)
+	if err != nil {
+		return "", err
 	}
+	decompilerName = decompiler.GetName()
 	service, ok := serviceMap[mappingType]
 	if !ok {
 		return "", errors.New("unknown mapping type")
 	}
+	if IsAvailable(mcVersion, mappingType, decompilerName) {
+		return global.GetSourceFolder(service, mcVersion, decompilerName), nil
+	}
 
-	slog.Info(fmt.Sprintf("Decompiling source type %s for %s", mappingType, mcVersion))
-	StartPending(mcVersion, mappingType)
-	path, err := service.Remap(mcVersion)
+	slog.Info(fmt.Sprintf("Decompiling source type %s for %s with %s", mappingType, mcVersion, decompilerName))
+	path, err := ensureRemapped(ctx, service, mcVersion)
 	if err != nil {
-		FailurePending(mcVersion, mappingType)
 		return "", err
 	}
-	sourcePath := global.GetSourceFolder(service, mcVersion)
-	params := util.ConcatMultipleSlices([][]string{global.Config.Decompiler.JavaParams, {"-jar", global.DecompilerPath}, global.Config.Decompiler.DecompilerParams, {path, sourcePath}})
-	err = util.ExecuteCommand(global.Config.JavaPath, params, true)
+	job.SetStage(ctx, job.StageDecompile)
+	sourcePath := global.GetSourceFolder(service, mcVersion, decompilerName)
+	err = decompiler.Decompile(ctx, path, sourcePath)
 	if err != nil {
-		FailurePending(mcVersion, mappingType)
+		// 不完整的源码会被当作可用，需要删除
+		if err := os.RemoveAll(sourcePath); err != nil {
+			slog.Error("Failed to remove partial source: " + err.Error())
+		}
 		return "", err
 	}
-	Done(mcVersion, mappingType)
+	job.SetStage(ctx, job.StageIndex)
+	if _, err := source.BuildIndex(sourcePath); err != nil {
+		slog.Error("Failed to index source, it will be rebuilt on first search: " + err.Error())
+	}
+	if _, err := source.BuildXref(path); err != nil {
+		slog.Error("Failed to build cross reference index, it will be rebuilt on first query: " + err.Error())
+	}
+	Done(mcVersion, mappingType, decompilerName)
 	slog.Info("Done in " + strconv.FormatInt(int64(time.Since(start)/1000000), 10) + "ms")
 	return sourcePath, nil
 }
+
+// DecompileClass 在完整源码不可用时只反编译一个类及其内部类，class 可以是类路径、点分类名或内部类名，
+// 重映射后的 jar 不存在时返回 os.ErrNotExist，正在重映射时返回 ErrRemapping，返回缓存单独反编译结果的目录
+func DecompileClass(ctx context.Context, mcVersion, mappingType, decompilerName, class string) (string, error) {
+	decompiler, err := GetDecompiler(decompilerName)
+	if err != nil {
+		return "", err
+	}
+	service, ok := serviceMap[mappingType]
+	if !ok {
+		return "", errors.New("unknown mapping type")
+	}
+	// 读取 jar 期间不允许重新生成，排队期间不持有读锁，否则等待写锁的任务会占住线程池
+	jarPath, release, err := lockRemappedJar(mcVersion, mappingType)
+	if err != nil {
+		return "", err
+	}
+	classPath, err := findOuterClass(jarPath, class)
+	release()
+	if err != nil {
+		return "", err
+	}
+	folder := global.GetClassCacheFolder(service, mcVersion, decompiler.GetName())
+	target := filepath.Join(folder, filepath.FromSlash(classPath)+".java")
+	if _, err := os.Stat(target); err == nil {
+		return folder, nil
+	}
+	// 在线程池中排队，优先于后台任务
+	memory := decompilerMemory(decompiler.GetName())
+	ctx = util.WithReservedMemory(ctx, memory)
+	err = util.RunTask(ctx, util.Task{Kind: TaskClass, Priority: util.PriorityHigh, Memory: memory, Run: func() error {
+		// 等待期间可能已经被其它请求反编译
+		if _, err := os.Stat(target); err == nil {
+			return nil
+		}
+		// 排队期间可能开始了重映射
+		jarPath, release, err := lockRemappedJar(mcVersion, mappingType)
+		if err != nil {
+			return err
+		}
+		defer release()
+		slog.Info(fmt.Sprintf("Decompiling %s of %s %s with %s", classPath, mappingType, mcVersion, decompiler.GetName()))
+		if err := decompiler.DecompileClass(ctx, jarPath, classPath, folder); err != nil {
+			os.Remove(target)
+			return err
+		}
+		return nil
+	}})
+	if err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(target); err != nil {
+		return "", errors.New("decompiler produced no output for " + classPath)
+	}
+	return folder, nil
+}
+
+// 重映射后的 jar 已存在时直接返回，否则先下载原版 jar 和映射再重映射
+func ensureRemapped(ctx context.Context, service Service, mcVersion string) (string, error) {
+	jarPath := global.GetRemappedPath(service, mcVersion)
+	lock := getRemapLock(jarPath)
+	lock.Lock()
+	defer lock.Unlock()
+	if _, err := os.Stat(jarPath); err == nil {
+		return jarPath, nil
+	}
+	job.SetStage(ctx, job.StageDownload)
+	if _, err := vanilla.GetMcJarPathContext(ctx, mcVersion); err != nil {
+		return "", err
+	}
+	if _, err := service.GetPathOrDownload(mcVersion); err != nil {
+		return "", err
+	}
+	job.SetStage(ctx, job.StageRemap)
+	path, err := service.Remap(ctx, mcVersion)
+	if err != nil {
+		// 不完整的 jar 会被当作已生成，需要删除
+		if err := os.Remove(jarPath); err != nil && !os.IsNotExist(err) {
+			slog.Error("Failed to remove partial jar: " + err.Error())
+		}
+		return "", err
+	}
+	return path, nil
+}
+
+// ReadRemappedClass 从重映射后的 jar 中读取一个类，jar 不存在时返回 os.ErrNotExist，正在重映射时返回 ErrRemapping
+func ReadRemappedClass(mcVersion, mappingType, class string) (*classfile.ClassFile, error) {
+	jarPath, release, err := lockRemappedJar(mcVersion, mappingType)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return classfile.ReadClass(jarPath, class)
+}
+
+// OpenRemappedJar 返回已生成的重映射 jar 及其 SHA-256，jar 不存在时返回 os.ErrNotExist，正在重映射时返回 ErrRemapping，
+// 读取完成后需要调用返回的函数释放读锁，在此之前 jar 不会被重新生成
+func OpenRemappedJar(mcVersion, mappingType string) (string, string, func(), error) {
+	jarPath, release, err := lockRemappedJar(mcVersion, mappingType)
+	if err != nil {
+		return "", "", nil, err
+	}
+	info, err := os.Stat(jarPath)
+	if err != nil {
+		release()
+		return "", "", nil, err
+	}
+	checksum, err := jarChecksum(jarPath, info)
+	if err != nil {
+		release()
+		return "", "", nil, err
+	}
+	return jarPath, checksum, release, nil
+}
+
+// 获取已生成的重映射 jar 的读锁，不等待正在进行的重映射，jar 不存在时返回 os.ErrNotExist
+func lockRemappedJar(mcVersion, mappingType string) (string, func(), error) {
+	if err := vanilla.CheckVersion(mcVersion); err != nil {
+		return "", nil, err
+	}
+	service, ok := serviceMap[mappingType]
+	if !ok {
+		return "", nil, errors.New("unknown mapping type")
+	}
+	jarPath := global.GetRemappedPath(service, mcVersion)
+	lock := getRemapLock(jarPath)
+	// 重映射需要几分钟，请求不能等待，由调用者返回重映射任务
+	if !lock.TryRLock() {
+		return "", nil, ErrRemapping
+	}
+	if _, err := os.Stat(jarPath); err != nil {
+		lock.RUnlock()
+		return "", nil, err
+	}
+	return jarPath, lock.RUnlock, nil
+}
+
+type checksumEntry struct {
+	modTime time.Time
+	size    int64
+	sum     string
+}
+
+// jar 未变化时复用之前计算的校验和
+func jarChecksum(jarPath string, info os.FileInfo) (string, error) {
+	checksumsLock.Lock()
+	defer checksumsLock.Unlock()
+	if entry, ok := checksums[jarPath]; ok && entry.modTime.Equal(info.ModTime()) && entry.size == info.Size() {
+		return entry.sum, nil
+	}
+	file, err := os.Open(jarPath)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	sum := hex.EncodeToString(hash.Sum(nil))
+	checksums[jarPath] = checksumEntry{modTime: info.ModTime(), size: info.Size(), sum: sum}
+	return sum, nil
+}
+
+// 在 jar 中查找类所在的顶层类，支持 a/b/Foo、a.b.Foo$Bar 和 a.b.Foo.Bar
+func findOuterClass(jarPath, class string) (string, error) {
+	jar, err := zip.OpenReader(jarPath)
+	if err != nil {
+		return "", err
+	}
+	defer jar.Close()
+	outer, _, _ := strings.Cut(strings.TrimSuffix(strings.ReplaceAll(class, "/", "."), ".java"), "$")
+	segments := strings.Split(outer, ".")
+	for i := len(segments); i > 0; i-- {
+		classPath := strings.Join(segments[:i], "/")
+		if _, err := fs.Stat(jar, classPath+".class"); err == nil {
+			return classPath, nil
+		}
+	}
+	return "", ErrClassNotFound
+}
+
+func getRemapLock(jarPath string) *sync.RWMutex {
+	remapLocksLock.Lock()
+	defer remapLocksLock.Unlock()
+	lock, ok := remapLocks[jarPath]
+	if !ok {
+		lock = &sync.RWMutex{}
+		remapLocks[jarPath] = lock
+	}
+	return lock
+}
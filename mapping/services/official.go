@@ -2,6 +2,7 @@ package services
 
 import (
 	"bufio"
+	"context"
 	"errors"
 	"fmt"
 	"os"
@@ -118,8 +119,8 @@ func (s *Official) LoadMapping(mcVersion string) (*map[java.SingleInfo]java.Sing
 	return &result, nil
 }
 
-func (s *Official) Remap(mcVersion string) (string, error) {
-	jarPath, err := vanilla.GetMcJarPath(mcVersion)
+func (s *Official) Remap(ctx context.Context, mcVersion string) (string, error) {
+	jarPath, err := vanilla.GetMcJarPathContext(ctx, mcVersion)
 	if err != nil {
 		return "", err
 	}
@@ -128,7 +129,9 @@ func (s *Official) Remap(mcVersion string) (string, error) {
 		return "", err
 	}
 	outputPath := global.GetRemappedPath(s, mcVersion)
-	err = util.ExecuteCommand(global.Config.JavaPath, []string{"-cp", global.ClassPath, global.ArtMainClass, "--input", jarPath, "--output", outputPath, "--map", mappingPath, "--reverse"}, true)
+	config := global.Config.Remapper
+	params := util.ConcatMultipleSlices([][]string{config.JavaParams, {"-cp", global.ClassPath, global.ArtMainClass, "--input", jarPath, "--output", outputPath, "--map", mappingPath, "--reverse"}})
+	err = util.RunCommand(ctx, global.Config.JavaPath, params, config.CommandOptions(true))
 	if err != nil {
 		return "", err
 	}
@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"bytes"
 	"compress/gzip"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -42,11 +43,11 @@ func (s *Yarn) GetPathOrDownload(mcVersion string) (string, error) {
 	}
 	body, err := network.Get(global.Config.Urls.FabricMeta + "/v2/versions/yarn")
 	if err != nil {
-		return "", errors.New("Unable to download yarn versions: " + err.Error())
+		return "", fmt.Errorf("Unable to download yarn versions: %w", err)
 	}
 	var versions []YarnVersion
 	if err := json.Unmarshal(body, &versions); err != nil {
-		return "", errors.New("Unable to unmarshal yarn versions: " + err.Error())
+		return "", fmt.Errorf("Unable to unmarshal yarn versions: %w", err)
 	}
 	var latestVersion *YarnVersion
 	for i := range versions {
@@ -62,11 +63,11 @@ func (s *Yarn) GetPathOrDownload(mcVersion string) (string, error) {
 	}
 	jar, err := network.Get(fmt.Sprintf(global.Config.Urls.FabricMaven+"/net/fabricmc/yarn/%s/yarn-%s-tiny.gz", latestVersion.Version, latestVersion.Version))
 	if err != nil {
-		return "", errors.New("Unable to download yarn mapping: " + err.Error())
+		return "", fmt.Errorf("Unable to download yarn mapping: %w", err)
 	}
 	data, err := getMappingsTinyFromGzip(jar)
 	if err != nil {
-		return "", errors.New("Unable to unzip yarn mapping: " + err.Error())
+		return "", fmt.Errorf("Unable to unzip yarn mapping: %w", err)
 	}
 	err = os.WriteFile(path, data, 0644)
 	if err != nil {
@@ -118,7 +119,7 @@ func (s *Yarn) LoadMapping(mcVersion string) (*map[java.SingleInfo]java.SingleIn
 			if !ok {
 				continue
 			}
-			notch, yarn := java.PackMethodInfo(split[3], split[1], split[2]), java.PackMethodInfo(split[5], yarnClass.Signature, "")
+			notch, yarn := java.PackMethodInfo(split[3], java.NormalizeClassName(split[1]), split[2]), java.PackMethodInfo(split[5], yarnClass.Class, "")
 			mapping[notch] = yarn
 			break
 		case "FIELD":
@@ -126,7 +127,7 @@ func (s *Yarn) LoadMapping(mcVersion string) (*map[java.SingleInfo]java.SingleIn
 			if !ok {
 				continue
 			}
-			notch, yarn := java.PackFieldInfo(split[3], split[1], split[2]), java.PackFieldInfo(split[5], yarnClass.Signature, "")
+			notch, yarn := java.PackFieldInfo(split[3], java.NormalizeClassName(split[1]), split[2]), java.PackFieldInfo(split[5], yarnClass.Class, "")
 			mapping[notch] = yarn
 			break
 		}
@@ -151,8 +152,8 @@ func (s *Yarn) LoadMapping(mcVersion string) (*map[java.SingleInfo]java.SingleIn
 	return &result, nil
 }
 
-func (s *Yarn) Remap(mcVersion string) (string, error) {
-	jarPath, err := vanilla.GetMcJarPath(mcVersion)
+func (s *Yarn) Remap(ctx context.Context, mcVersion string) (string, error) {
+	jarPath, err := vanilla.GetMcJarPathContext(ctx, mcVersion)
 	if err != nil {
 		return "", err
 	}
@@ -161,7 +162,9 @@ func (s *Yarn) Remap(mcVersion string) (string, error) {
 		return "", err
 	}
 	outputPath := global.GetRemappedPath(s, mcVersion)
-	err = util.ExecuteCommand(global.Config.JavaPath, []string{"-cp", global.ClassPath, global.TinyRemapperMainClass, jarPath, outputPath, mappingPath, "official", "named"}, false)
+	config := global.Config.Remapper
+	params := util.ConcatMultipleSlices([][]string{config.JavaParams, {"-cp", global.ClassPath, global.TinyRemapperMainClass, jarPath, outputPath, mappingPath, "official", "named"}})
+	err = util.RunCommand(ctx, global.Config.JavaPath, params, config.CommandOptions(false))
 	if err != nil {
 		return "", err
 	}
@@ -5,22 +5,16 @@ import (
 	"pluto/util"
 )
 
+// AvailableConfig Official 和 Yarn 记录 Vineflower 的结果，其它反编译器记录在 Others 中，键为 类型/反编译器
 type AvailableConfig struct {
-	Official []string `json:"official"`
-	Yarn     []string `json:"yarn"`
-}
-
-type TaskInfo struct {
-	MappingType string
-	Version     string
+	Official []string            `json:"official"`
+	Yarn     []string            `json:"yarn"`
+	Others   map[string][]string `json:"others,omitempty"`
 }
 
 const configPath = "cache/source-available.json"
 
-var (
-	availableConfig = AvailableConfig{}
-	pendingTasks    = make(map[TaskInfo]struct{})
-)
+var availableConfig = AvailableConfig{}
 
 func InitMappingConfig() error {
 	config, err := util.LoadConfig[AvailableConfig](configPath)
@@ -31,7 +25,10 @@ func InitMappingConfig() error {
 	return nil
 }
 
-func IsAvailable(mcVersion, mappingType string) bool {
+func IsAvailable(mcVersion, mappingType, decompiler string) bool {
+	if decompiler != "vineflower" {
+		return util.Contains(availableConfig.Others[mappingType+"/"+decompiler], mcVersion)
+	}
 	switch mappingType {
 	case "official":
 		return util.Contains(availableConfig.Official, mcVersion)
@@ -42,38 +39,17 @@ func IsAvailable(mcVersion, mappingType string) bool {
 	}
 }
 
-func IsPending(mcVersion, mappingType string) bool {
-	_, ok := pendingTasks[TaskInfo{
-		MappingType: mappingType,
-		Version:     mcVersion,
-	}]
-	return ok
-}
-
-func CanAddTask(mcVersion, mappingType string) bool {
-	return !IsAvailable(mcVersion, mappingType) && !IsPending(mcVersion, mappingType)
-}
-
-func StartPending(mcVersion, mappingType string) {
-	pendingTasks[TaskInfo{
-		MappingType: mappingType,
-		Version:     mcVersion,
-	}] = struct{}{}
-}
-
-func FailurePending(mcVersion, mappingType string) {
-	delete(pendingTasks, TaskInfo{
-		MappingType: mappingType,
-		Version:     mcVersion,
-	})
-}
-
-func Done(mcVersion, mappingType string) {
-	FailurePending(mcVersion, mappingType)
-	switch mappingType {
-	case "official":
+func Done(mcVersion, mappingType, decompiler string) {
+	switch {
+	case decompiler != "vineflower":
+		if availableConfig.Others == nil {
+			availableConfig.Others = make(map[string][]string)
+		}
+		key := mappingType + "/" + decompiler
+		availableConfig.Others[key] = append(availableConfig.Others[key], mcVersion)
+	case mappingType == "official":
 		availableConfig.Official = append(availableConfig.Official, mcVersion)
-	case "yarn":
+	case mappingType == "yarn":
 		availableConfig.Yarn = append(availableConfig.Yarn, mcVersion)
 	}
 	err := util.SaveConfig(availableConfig, configPath)
@@ -0,0 +1,67 @@
+package source
+
+import (
+	"archive/zip"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+const manifest = "Manifest-Version: 1.0\r\nCreated-By: Pluto\r\n\r\n"
+
+// WriteArchive 将源码目录（或其中某个包）打包写入 w，路径相对源码根目录以便 IDE 识别，
+// withManifest 为 true 时额外写入 META-INF/MANIFEST.MF 作为 sources jar
+func WriteArchive(w io.Writer, folder, pkg string, withManifest bool) error {
+	root, err := ResolvePath(folder, PackageToPath(pkg))
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(root); err != nil {
+		return err
+	}
+	archive := zip.NewWriter(w)
+	if withManifest {
+		entry, err := archive.Create("META-INF/MANIFEST.MF")
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(entry, manifest); err != nil {
+			return err
+		}
+	}
+	err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(folder, path)
+		if err != nil {
+			return err
+		}
+		header, err := zip.FileInfoHeader(info)
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(rel)
+		header.Method = zip.Deflate
+		entry, err := archive.CreateHeader(header)
+		if err != nil {
+			return err
+		}
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		_, err = io.Copy(entry, file)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return archive.Close()
+}
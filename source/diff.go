@@ -0,0 +1,309 @@
+package source
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+type DiffSummary struct {
+	Added    []string `json:"added"`
+	Removed  []string `json:"removed"`
+	Modified []string `json:"modified"`
+}
+
+type DiffResult struct {
+	Summary   DiffSummary       `json:"summary"`
+	Diffs     map[string]string `json:"diffs"` // 类路径 -> unified diff
+	Truncated bool              `json:"truncated,omitempty"`
+}
+
+const (
+	diffContext  = 3
+	maxEditCount = 2000 // 超过后剩余部分整体视为替换，避免 Myers 算法耗尽内存
+)
+
+// DiffTrees 比较两个源码目录，class 不为空时只比较该类，最多生成 maxDiffs 个类的 diff
+func DiffTrees(fromFolder, toFolder, class string, maxDiffs int) (*DiffResult, error) {
+	var fromFiles, toFiles map[string]struct{}
+	var err error
+	if class != "" {
+		file := ClassFilePath(strings.ReplaceAll(class, "/", ".")) + ".java"
+		fromFiles, toFiles = existingFiles(fromFolder, file), existingFiles(toFolder, file)
+	} else {
+		if fromFiles, err = listJavaFiles(fromFolder); err != nil {
+			return nil, err
+		}
+		if toFiles, err = listJavaFiles(toFolder); err != nil {
+			return nil, err
+		}
+	}
+
+	result := &DiffResult{
+		Summary: DiffSummary{Added: []string{}, Removed: []string{}, Modified: []string{}},
+		Diffs:   make(map[string]string),
+	}
+	for file := range fromFiles {
+		if _, ok := toFiles[file]; !ok {
+			result.Summary.Removed = append(result.Summary.Removed, strings.TrimSuffix(file, ".java"))
+		}
+	}
+	var common []string
+	for file := range toFiles {
+		if _, ok := fromFiles[file]; ok {
+			common = append(common, file)
+		} else {
+			result.Summary.Added = append(result.Summary.Added, strings.TrimSuffix(file, ".java"))
+		}
+	}
+	sort.Strings(common)
+	for _, file := range common {
+		a, err := os.ReadFile(filepath.Join(fromFolder, filepath.FromSlash(file)))
+		if err != nil {
+			return nil, err
+		}
+		b, err := os.ReadFile(filepath.Join(toFolder, filepath.FromSlash(file)))
+		if err != nil {
+			return nil, err
+		}
+		if bytes.Equal(a, b) {
+			continue
+		}
+		name := strings.TrimSuffix(file, ".java")
+		result.Summary.Modified = append(result.Summary.Modified, name)
+		if len(result.Diffs) >= maxDiffs {
+			result.Truncated = true
+			continue
+		}
+		result.Diffs[name] = UnifiedDiff("a/"+file, "b/"+file, splitLines(string(a)), splitLines(string(b)))
+	}
+	sort.Strings(result.Summary.Added)
+	sort.Strings(result.Summary.Removed)
+	return result, nil
+}
+
+func existingFiles(folder, file string) map[string]struct{} {
+	files := make(map[string]struct{})
+	if path, err := ResolvePath(folder, file); err == nil {
+		if _, err := os.Stat(path); err == nil {
+			files[file] = struct{}{}
+		}
+	}
+	return files
+}
+
+func listJavaFiles(folder string) (map[string]struct{}, error) {
+	files := make(map[string]struct{})
+	err := filepath.WalkDir(folder, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !strings.HasSuffix(path, ".java") {
+			return err
+		}
+		rel, err := filepath.Rel(folder, path)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = struct{}{}
+		return nil
+	})
+	return files, err
+}
+
+func splitLines(s string) []string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+type editKind int
+
+const (
+	editEqual editKind = iota
+	editDelete
+	editInsert
+)
+
+type edit struct {
+	kind editKind
+	a, b int // 在 a、b 中的行下标
+}
+
+// UnifiedDiff 生成带 3 行上下文的 unified diff，内容相同时返回空串
+func UnifiedDiff(aName, bName string, a, b []string) string {
+	edits := diffLines(a, b)
+	var out strings.Builder
+	for start := 0; start < len(edits); {
+		// 找到下一处修改
+		for start < len(edits) && edits[start].kind == editEqual {
+			start++
+		}
+		if start == len(edits) {
+			break
+		}
+		hunkStart := max(0, start-diffContext)
+		end := start
+		for end < len(edits) {
+			if edits[end].kind != editEqual {
+				end++
+				continue
+			}
+			// 两处修改之间的相同行不超过 2 倍上下文时合并为一个 hunk
+			run := end
+			for run < len(edits) && edits[run].kind == editEqual {
+				run++
+			}
+			if run == len(edits) || run-end > 2*diffContext {
+				end = min(end+diffContext, len(edits))
+				break
+			}
+			end = run
+		}
+		if out.Len() == 0 {
+			fmt.Fprintf(&out, "--- %s\n+++ %s\n", aName, bName)
+		}
+		writeHunk(&out, edits[hunkStart:end], a, b)
+		start = end
+	}
+	return out.String()
+}
+
+func writeHunk(out *strings.Builder, edits []edit, a, b []string) {
+	aStart, bStart, aCount, bCount := -1, -1, 0, 0
+	for _, e := range edits {
+		if e.kind != editInsert {
+			if aStart < 0 {
+				aStart = e.a
+			}
+			aCount++
+		}
+		if e.kind != editDelete {
+			if bStart < 0 {
+				bStart = e.b
+			}
+			bCount++
+		}
+	}
+	// 空区间按照惯例使用前一行的行号
+	if aStart < 0 {
+		aStart = edits[0].a - 1
+	}
+	if bStart < 0 {
+		bStart = edits[0].b - 1
+	}
+	fmt.Fprintf(out, "@@ -%d,%d +%d,%d @@\n", aStart+1, aCount, bStart+1, bCount)
+	for _, e := range edits {
+		switch e.kind {
+		case editEqual:
+			out.WriteString(" " + a[e.a] + "\n")
+		case editDelete:
+			out.WriteString("-" + a[e.a] + "\n")
+		case editInsert:
+			out.WriteString("+" + b[e.b] + "\n")
+		}
+	}
+}
+
+// diffLines 先去掉公共前后缀，再用 Myers 算法比较中间部分
+func diffLines(a, b []string) []edit {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	var edits []edit
+	for i := 0; i < prefix; i++ {
+		edits = append(edits, edit{editEqual, i, i})
+	}
+	edits = append(edits, myers(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix], prefix, prefix)...)
+	for i := 0; i < suffix; i++ {
+		edits = append(edits, edit{editEqual, len(a) - suffix + i, len(b) - suffix + i})
+	}
+	return edits
+}
+
+func myers(a, b []string, aOffset, bOffset int) []edit {
+	n, m := len(a), len(b)
+	limit := min(n+m, maxEditCount)
+	offset := limit + 1
+	v := make([]int, 2*offset+1)
+	var trace [][]int
+	found := false
+	for d := 0; d <= limit && !found; d++ {
+		// 只保存本轮会用到的 [-d-1, d+1] 区间
+		trace = append(trace, append([]int(nil), v[offset-d-1:offset+d+2]...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				found = true
+				break
+			}
+		}
+	}
+	if !found {
+		return replaceAll(n, m, aOffset, bOffset)
+	}
+
+	// 回溯得到编辑序列
+	var edits []edit
+	x, y := n, m
+	for d := len(trace) - 1; d >= 0; d-- {
+		v, base := trace[d], d+1
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && v[base+k-1] < v[base+k+1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := v[base+prevK]
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			x--
+			y--
+			edits = append(edits, edit{editEqual, aOffset + x, bOffset + y})
+		}
+		if d > 0 {
+			if x == prevX {
+				y--
+				edits = append(edits, edit{editInsert, aOffset + x, bOffset + y})
+			} else {
+				x--
+				edits = append(edits, edit{editDelete, aOffset + x, bOffset + y})
+			}
+		}
+	}
+	for i, j := 0, len(edits)-1; i < j; i, j = i+1, j-1 {
+		edits[i], edits[j] = edits[j], edits[i]
+	}
+	return edits
+}
+
+func replaceAll(n, m, aOffset, bOffset int) []edit {
+	edits := make([]edit, 0, n+m)
+	for i := 0; i < n; i++ {
+		edits = append(edits, edit{editDelete, aOffset + i, bOffset})
+	}
+	for j := 0; j < m; j++ {
+		edits = append(edits, edit{editInsert, aOffset + n, bOffset + j})
+	}
+	return edits
+}
@@ -0,0 +1,234 @@
+package source
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"os"
+	"pluto/mapping/java"
+	"strings"
+)
+
+type ViewOptions struct {
+	Title string
+	// Link 生成其它类页面的链接，参数为类路径，如 net/minecraft/Foo
+	Link func(classPath string) string
+	// Describe 返回类或成员在其它映射中的名称，member 为空时描述类本身，无结果时返回空串
+	Describe func(class, member string) string
+}
+
+const viewStyle = `body{margin:0;background:#fafafa;color:#24292e;font:13px/1.5 ui-monospace,Consolas,monospace}
+h1{font-size:14px;margin:0;padding:8px 12px;background:#eee;border-bottom:1px solid #ddd}
+table{border-collapse:collapse}td{padding:0 12px;vertical-align:top}pre{margin:0;white-space:pre}
+td.ln{text-align:right;user-select:none}td.ln a{color:#999;text-decoration:none}tr:target{background:#fff8c5}
+.kw{color:#d73a49}.str{color:#032f62}.num{color:#005cc5}.com{color:#6a737d;font-style:italic}.ann{color:#e36209}
+a.type{color:#6f42c1;text-decoration:none}a.type:hover{text-decoration:underline}[title]{border-bottom:1px dotted #999}`
+
+// RenderHTML 将 Java 源码渲染为带语法高亮、行号锚点和类型链接的页面，
+// class 为当前文件的类路径，用于解析同包的类型引用
+func RenderHTML(w io.Writer, folder, class string, content []byte, options ViewOptions) error {
+	tokens := java.Tokenize(string(content))
+	resolver := newTypeResolver(folder, class, tokens)
+	currentClass := strings.ReplaceAll(class, "/", ".")
+
+	lines := []string{""}
+	var current strings.Builder
+	write := func(text, open, close string) {
+		for i, part := range strings.Split(text, "\n") {
+			if i > 0 {
+				lines[len(lines)-1] = current.String()
+				lines = append(lines, "")
+				current.Reset()
+			}
+			if part != "" {
+				current.WriteString(open + html.EscapeString(part) + close)
+			}
+		}
+	}
+
+	prev := -1 // 上一个非空白 Token 的下标
+	for i, token := range tokens {
+		open, close := "", ""
+		switch token.Kind {
+		case java.TokenKeyword:
+			open, close = `<span class="kw">`, "</span>"
+		case java.TokenString:
+			open, close = `<span class="str">`, "</span>"
+		case java.TokenNumber:
+			open, close = `<span class="num">`, "</span>"
+		case java.TokenComment:
+			open, close = `<span class="com">`, "</span>"
+		case java.TokenIdentifier:
+			open, close = identifierTags(tokens, i, prev, currentClass, resolver, options)
+		}
+		write(token.Text, open, close)
+		if token.Kind != java.TokenWhitespace && token.Kind != java.TokenComment {
+			prev = i
+		}
+	}
+	lines[len(lines)-1] = current.String()
+
+	out := &strings.Builder{}
+	fmt.Fprintf(out, "<!DOCTYPE html>\n<html><head><meta charset=\"utf-8\"><title>%s</title><style>%s</style></head><body>\n", html.EscapeString(options.Title), viewStyle)
+	fmt.Fprintf(out, "<h1>%s</h1>\n<table>\n", html.EscapeString(options.Title))
+	for i, line := range lines {
+		fmt.Fprintf(out, "<tr id=\"L%d\"><td class=\"ln\"><a href=\"#L%d\">%d</a></td><td><pre>%s</pre></td></tr>\n", i+1, i+1, i+1, line)
+	}
+	out.WriteString("</table>\n</body></html>\n")
+	_, err := io.WriteString(w, out.String())
+	return err
+}
+
+func identifierTags(tokens []java.Token, i, prev int, currentClass string, resolver *typeResolver, options ViewOptions) (string, string) {
+	name := tokens[i].Text
+	if prev >= 0 && tokens[prev].Text == "@" {
+		return `<span class="ann">`, "</span>"
+	}
+	// 包名和 import 中的限定名不处理
+	if resolver.inQualifiedName(i) {
+		return "", ""
+	}
+	// 形如 Foo.bar 的静态访问成员属于 Foo，this.bar 属于当前类
+	owner := currentClass
+	if prev >= 0 && tokens[prev].Text == "." {
+		owner = ""
+		if p := previousSignificant(tokens, prev); p >= 0 && tokens[p].Text == "this" {
+			owner = currentClass
+		} else if p >= 0 && tokens[p].Kind == java.TokenIdentifier {
+			if full, ok := resolver.resolve(tokens[p].Text); ok {
+				owner = strings.ReplaceAll(full, "/", ".")
+			}
+		}
+	}
+	if full, ok := resolver.resolve(name); ok && (prev < 0 || tokens[prev].Text != ".") {
+		title := describe(options, strings.ReplaceAll(full, "/", "."), "")
+		return fmt.Sprintf(`<a class="type" href="%s"%s>`, html.EscapeString(options.Link(full)), title), "</a>"
+	}
+	if owner == "" {
+		return "", ""
+	}
+	if title := describe(options, owner, name); title != "" {
+		return "<span" + title + ">", "</span>"
+	}
+	return "", ""
+}
+
+func describe(options ViewOptions, class, member string) string {
+	if options.Describe == nil {
+		return ""
+	}
+	if text := options.Describe(class, member); text != "" {
+		return ` title="` + html.EscapeString(text) + `"`
+	}
+	return ""
+}
+
+func previousSignificant(tokens []java.Token, i int) int {
+	for i--; i >= 0; i-- {
+		if tokens[i].Kind != java.TokenWhitespace && tokens[i].Kind != java.TokenComment {
+			return i
+		}
+	}
+	return -1
+}
+
+// typeResolver 根据 import 和当前包把简单类名解析为源码目录中存在的类路径
+type typeResolver struct {
+	folder    string
+	pkg       string
+	imports   map[string]string // 简单名 -> 类路径
+	wildcards []string          // 通配导入的包路径
+	qualified map[int]struct{}  // 属于 package/import 语句的 Token 下标
+	cache     map[string]string
+}
+
+func newTypeResolver(folder, class string, tokens []java.Token) *typeResolver {
+	r := &typeResolver{
+		folder:    folder,
+		imports:   make(map[string]string),
+		qualified: make(map[int]struct{}),
+		cache:     make(map[string]string),
+	}
+	if idx := strings.LastIndex(class, "/"); idx >= 0 {
+		r.pkg = class[:idx]
+	}
+	for i := 0; i < len(tokens); i++ {
+		if tokens[i].Kind != java.TokenKeyword || (tokens[i].Text != "import" && tokens[i].Text != "package") {
+			continue
+		}
+		statement := tokens[i].Text
+		var parts []string
+		static := false
+		for i++; i < len(tokens) && tokens[i].Text != ";"; i++ {
+			switch {
+			case tokens[i].Text == "static":
+				static = true
+			case tokens[i].Kind == java.TokenIdentifier || tokens[i].Text == "*":
+				parts = append(parts, tokens[i].Text)
+				r.qualified[i] = struct{}{}
+			}
+		}
+		if statement == "package" || static || len(parts) == 0 {
+			continue
+		}
+		if parts[len(parts)-1] == "*" {
+			r.wildcards = append(r.wildcards, strings.Join(parts[:len(parts)-1], "/"))
+		} else {
+			r.imports[parts[len(parts)-1]] = strings.Join(parts, "/")
+		}
+	}
+	return r
+}
+
+func (r *typeResolver) inQualifiedName(i int) bool {
+	_, ok := r.qualified[i]
+	return ok
+}
+
+func isUpper(name string) bool {
+	return name != "" && name[0] >= 'A' && name[0] <= 'Z'
+}
+
+func (r *typeResolver) resolve(name string) (string, bool) {
+	if !isUpper(name) {
+		return "", false
+	}
+	if path, ok := r.cache[name]; ok {
+		return path, path != ""
+	}
+	candidates := []string{}
+	if imported, ok := r.imports[name]; ok {
+		// 导入内部类时类路径指向外部类的文件
+		for parts := strings.Split(imported, "/"); len(parts) > 0 && isUpper(parts[len(parts)-1]); parts = parts[:len(parts)-1] {
+			candidates = append(candidates, strings.Join(parts, "/"))
+		}
+	}
+	candidates = append(candidates, joinPackage(r.pkg, name))
+	for _, wildcard := range r.wildcards {
+		candidates = append(candidates, joinPackage(wildcard, name))
+	}
+	for _, candidate := range candidates {
+		if r.exists(candidate) {
+			r.cache[name] = candidate
+			return candidate, true
+		}
+	}
+	r.cache[name] = ""
+	return "", false
+}
+
+func (r *typeResolver) exists(classPath string) bool {
+	path, err := ResolvePath(r.folder, classPath+".java")
+	if err != nil {
+		return false
+	}
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
+
+func joinPackage(pkg, name string) string {
+	if pkg == "" {
+		return name
+	}
+	return pkg + "/" + name
+}
@@ -0,0 +1,164 @@
+package source
+
+import (
+	"errors"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Index 源码全文索引，记录每个单词（小写）出现在哪些文件中
+type Index struct {
+	Files    []string   // 相对源码目录的路径，使用 / 分隔
+	Tokens   []string   // 已排序
+	Postings [][]uint32 // 与 Tokens 一一对应的文件下标
+}
+
+const maxTokenLength = 64
+
+var (
+	indexes     = map[string]*Index{}
+	indexesLock sync.Mutex
+)
+
+func indexPath(folder string) string {
+	return filepath.Clean(folder) + ".index"
+}
+
+// BuildIndex 扫描源码目录生成索引并保存到目录旁的 .index 文件
+func BuildIndex(folder string) (*Index, error) {
+	start := time.Now()
+	slog.Info("Building source index for " + folder)
+	postings := make(map[string][]uint32)
+	index := &Index{}
+	err := filepath.WalkDir(folder, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !strings.HasSuffix(path, ".java") {
+			return err
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(folder, path)
+		if err != nil {
+			return err
+		}
+		fileId := uint32(len(index.Files))
+		index.Files = append(index.Files, filepath.ToSlash(rel))
+		for token := range tokenize(string(content)) {
+			postings[token] = append(postings[token], fileId)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	index.Tokens = make([]string, 0, len(postings))
+	for token := range postings {
+		index.Tokens = append(index.Tokens, token)
+	}
+	sort.Strings(index.Tokens)
+	index.Postings = make([][]uint32, len(index.Tokens))
+	for i, token := range index.Tokens {
+		index.Postings[i] = postings[token]
+	}
+	if err := saveGob(indexPath(folder), index); err != nil {
+		slog.Error("Failed to save source index: " + err.Error())
+	}
+	indexesLock.Lock()
+	indexes[folder] = index
+	indexesLock.Unlock()
+	slog.Info("Indexed " + folder + " in " + time.Since(start).String())
+	return index, nil
+}
+
+// GetIndex 依次从内存、磁盘获取索引，都没有时重新构建
+func GetIndex(folder string) (*Index, error) {
+	indexesLock.Lock()
+	index, ok := indexes[folder]
+	indexesLock.Unlock()
+	if ok {
+		return index, nil
+	}
+	index = &Index{}
+	err := loadGob(indexPath(folder), index)
+	if err == nil {
+		indexesLock.Lock()
+		indexes[folder] = index
+		indexesLock.Unlock()
+		return index, nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		slog.Warn("Failed to load source index, rebuilding: " + err.Error())
+	}
+	return BuildIndex(folder)
+}
+
+// DropIndex 删除内存和磁盘中的索引，源码目录被清理时调用
+func DropIndex(folder string) {
+	indexesLock.Lock()
+	delete(indexes, folder)
+	indexesLock.Unlock()
+	_ = os.Remove(indexPath(folder))
+}
+
+// Candidates 返回可能包含所有单词的文件下标，words 为空时返回 nil 表示无法过滤
+func (index *Index) Candidates(words []string) []uint32 {
+	var result map[uint32]struct{}
+	for _, word := range words {
+		word = strings.ToLower(word)
+		matched := make(map[uint32]struct{})
+		// 查询中的单词可能只是某个单词的一部分，因此按包含关系匹配
+		for i, token := range index.Tokens {
+			if !strings.Contains(token, word) {
+				continue
+			}
+			for _, fileId := range index.Postings[i] {
+				if result == nil {
+					matched[fileId] = struct{}{}
+				} else if _, ok := result[fileId]; ok {
+					matched[fileId] = struct{}{}
+				}
+			}
+		}
+		result = matched
+		if len(result) == 0 {
+			break
+		}
+	}
+	if result == nil {
+		return nil
+	}
+	ids := make([]uint32, 0, len(result))
+	for id := range result {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// 按字母、数字和下划线切分单词，统一转为小写
+func tokenize(content string) map[string]struct{} {
+	tokens := make(map[string]struct{})
+	for _, word := range splitWords(content) {
+		if len(word) <= maxTokenLength {
+			tokens[strings.ToLower(word)] = struct{}{}
+		}
+	}
+	return tokens
+}
+
+func splitWords(s string) []string {
+	return strings.FieldsFunc(s, func(r rune) bool {
+		return !isWordRune(r)
+	})
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r > 0x7F
+}
@@ -0,0 +1,507 @@
+package source
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"pluto/mapping/java"
+	"strconv"
+	"strings"
+)
+
+// Declaration 源码中的类或成员声明及其所在行范围
+type Declaration struct {
+	Kind       string // class, method, constructor 或 field
+	Name       string
+	Parameters []string // 参数的简单类型名，仅方法和构造器
+	StartLine  int
+	EndLine    int
+	Children   []*Declaration // 仅类
+}
+
+var ErrNotFound = errors.New("cannot find the requested class or member")
+
+// LocateClass 将 net/minecraft/Foo、net.minecraft.Foo$Bar 或 net.minecraft.Foo.Bar 这样的类名
+// 解析为源码文件的类路径和内部类名列表
+func LocateClass(folder, class string) (string, []string, error) {
+	class = strings.TrimSuffix(strings.ReplaceAll(class, "/", "."), ".java")
+	outer, nested, _ := strings.Cut(class, "$")
+	var inner []string
+	if nested != "" {
+		inner = strings.Split(nested, "$")
+	}
+	segments := strings.Split(outer, ".")
+	for i := len(segments); i > 0; i-- {
+		classPath := strings.Join(segments[:i], "/")
+		path, err := ResolvePath(folder, classPath+".java")
+		if err != nil {
+			return "", nil, err
+		}
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return classPath, append(segments[i:], inner...), nil
+		}
+	}
+	return "", nil, ErrNotFound
+}
+
+// ParseLineRange 解析形如 120-180 或 120 的行范围
+func ParseLineRange(s string) (int, int, error) {
+	startText, endText, found := strings.Cut(s, "-")
+	start, err := strconv.Atoi(strings.TrimSpace(startText))
+	if err != nil || start < 1 {
+		return 0, 0, fmt.Errorf("invalid line range %q", s)
+	}
+	if !found {
+		return start, start, nil
+	}
+	end, err := strconv.Atoi(strings.TrimSpace(endText))
+	if err != nil || end < start {
+		return 0, 0, fmt.Errorf("invalid line range %q", s)
+	}
+	return start, end, nil
+}
+
+// FindRange 在源码中查找内部类或成员的行范围，member 可以是 名称 或 名称(描述符)，
+// 构造器使用 <init>
+func FindRange(content, classPath string, inner []string, member string) (int, int, error) {
+	declarations := ParseDeclarations(content)
+	name := classPath[strings.LastIndexByte(classPath, '/')+1:]
+	current := findDeclaration(declarations, "class", name)
+	for _, n := range inner {
+		if current == nil {
+			break
+		}
+		current = findDeclaration(current.Children, "class", n)
+	}
+	if current == nil {
+		return 0, 0, ErrNotFound
+	}
+	if member == "" {
+		return current.StartLine, current.EndLine, nil
+	}
+	memberName, descriptor, _ := strings.Cut(member, "(")
+	var parameters []string
+	if descriptor != "" {
+		parameters = descriptorParameters("(" + descriptor)
+	}
+	var best *Declaration
+	bestScore := -1
+	for _, d := range current.Children {
+		if d.Kind == "class" || !(d.Name == memberName || memberName == "<init>" && d.Kind == "constructor") {
+			continue
+		}
+		if descriptor == "" {
+			best = d
+			break
+		}
+		if d.Kind == "field" || len(d.Parameters) != len(parameters) {
+			continue
+		}
+		score := 0
+		for i, p := range parameters {
+			if d.Parameters[i] == p {
+				score++
+			}
+		}
+		if score > bestScore {
+			best, bestScore = d, score
+		}
+	}
+	if best == nil {
+		return 0, 0, ErrNotFound
+	}
+	return best.StartLine, best.EndLine, nil
+}
+
+func findDeclaration(declarations []*Declaration, kind, name string) *Declaration {
+	for _, d := range declarations {
+		if d.Kind == kind && d.Name == name {
+			return d
+		}
+	}
+	return nil
+}
+
+// 将方法描述符的参数转为源码中的简单类型名，如 (I[Lnet/Foo$Bar;)V -> int, Bar[]
+func descriptorParameters(descriptor string) []string {
+	var result []string
+	end := strings.IndexByte(descriptor, ')')
+	if !strings.HasPrefix(descriptor, "(") || end < 0 {
+		return nil
+	}
+	params := descriptor[1:end]
+	for i := 0; i < len(params); {
+		dims := 0
+		for i < len(params) && params[i] == '[' {
+			dims++
+			i++
+		}
+		if i >= len(params) {
+			break
+		}
+		var name string
+		if params[i] == 'L' {
+			semicolon := strings.IndexByte(params[i:], ';')
+			if semicolon < 0 {
+				break
+			}
+			name = simpleTypeName(params[i+1 : i+semicolon])
+			i += semicolon + 1
+		} else {
+			name = primitiveNames[params[i]]
+			i++
+		}
+		result = append(result, name+strings.Repeat("[]", dims))
+	}
+	return result
+}
+
+var primitiveNames = map[byte]string{'Z': "boolean", 'B': "byte", 'C': "char", 'S': "short", 'I': "int",
+	'J': "long", 'F': "float", 'D': "double", 'V': "void"}
+
+func simpleTypeName(name string) string {
+	if idx := strings.LastIndexAny(name, "/.$"); idx >= 0 {
+		return name[idx+1:]
+	}
+	return name
+}
+
+// ParseDeclarations 粗略解析源码中的类型和成员声明，不做完整语法分析
+func ParseDeclarations(content string) []*Declaration {
+	p := &declarationParser{}
+	for _, token := range java.Tokenize(content) {
+		if token.Kind != java.TokenWhitespace && token.Kind != java.TokenComment {
+			p.tokens = append(p.tokens, token)
+		}
+	}
+	var result []*Declaration
+	for p.i < len(p.tokens) {
+		if d := p.parseMember(); d != nil {
+			result = append(result, d)
+		}
+	}
+	return result
+}
+
+type declarationParser struct {
+	tokens []java.Token
+	i      int
+}
+
+func (p *declarationParser) text(i int) string {
+	if i < 0 || i >= len(p.tokens) {
+		return ""
+	}
+	return p.tokens[i].Text
+}
+
+// 跳过从当前位置开始的成对括号，返回右括号所在行
+func (p *declarationParser) skipBalanced() int {
+	depth := 0
+	for ; p.i < len(p.tokens); p.i++ {
+		switch p.tokens[p.i].Text {
+		case "(", "{", "[":
+			depth++
+		case ")", "}", "]":
+			depth--
+			if depth <= 0 {
+				line := p.tokens[p.i].Line
+				p.i++
+				return line
+			}
+		}
+	}
+	return p.tokens[len(p.tokens)-1].Line
+}
+
+func (p *declarationParser) skipAnnotation() {
+	p.i++ // @
+	for p.i < len(p.tokens) && (p.tokens[p.i].Kind == java.TokenIdentifier || p.text(p.i) == ".") {
+		p.i++
+	}
+	if p.text(p.i) == "(" {
+		p.skipBalanced()
+	}
+}
+
+func isTypeKeyword(s string) bool {
+	return s == "class" || s == "interface" || s == "enum" || s == "record"
+}
+
+// parseMember 解析一个成员声明，初始化块和无法识别的内容返回 nil
+func (p *declarationParser) parseMember() *Declaration {
+	start := p.i
+	startLine := p.tokens[p.i].Line
+	parenStart := -1
+	for p.i < len(p.tokens) {
+		token := p.tokens[p.i]
+		switch {
+		case token.Text == "}" || token.Text == ")":
+			// 不属于当前成员，交给上层处理
+			if p.i == start {
+				p.i++
+			}
+			return nil
+		case token.Text == ";":
+			p.i++
+			if parenStart >= 0 {
+				return p.method(start, parenStart, startLine, token.Line)
+			}
+			return p.field(start, p.i-1, startLine, token.Line)
+		case token.Text == "@" && p.text(p.i+1) != "interface":
+			p.skipAnnotation()
+		case token.Kind == java.TokenKeyword && isTypeKeyword(token.Text) || token.Text == "@" && p.text(p.i+1) == "interface":
+			if token.Text == "@" {
+				p.i++
+			}
+			// record 是上下文关键字，后面不是标识符时按普通名称处理
+			if token.Text == "record" && p.i+1 < len(p.tokens) && p.tokens[p.i+1].Kind != java.TokenIdentifier {
+				p.i++
+				continue
+			}
+			return p.parseClass(token.Text, startLine)
+		case token.Text == "(" && parenStart < 0:
+			parenStart = p.i
+			p.skipBalanced()
+		case token.Text == "=" && parenStart < 0:
+			equals := p.i
+			p.skipInitializer()
+			return p.field(start, equals, startLine, p.tokens[p.i-1].Line)
+		case token.Text == "{":
+			if parenStart < 0 {
+				// 初始化块
+				p.skipBalanced()
+				return nil
+			}
+			endLine := p.skipBalanced()
+			return p.method(start, parenStart, startLine, endLine)
+		default:
+			p.i++
+		}
+	}
+	return nil
+}
+
+// 跳过字段初始化表达式直到分号，表达式中可能包含 lambda 或匿名类
+func (p *declarationParser) skipInitializer() {
+	depth := 0
+	for ; p.i < len(p.tokens); p.i++ {
+		switch p.tokens[p.i].Text {
+		case "(", "{", "[":
+			depth++
+		case ")", "}", "]":
+			depth--
+			if depth < 0 {
+				return
+			}
+		case ";":
+			if depth == 0 {
+				p.i++
+				return
+			}
+		}
+	}
+}
+
+func (p *declarationParser) field(start, end, startLine, endLine int) *Declaration {
+	for i := end - 1; i >= start; i-- {
+		if p.tokens[i].Kind == java.TokenIdentifier {
+			return &Declaration{Kind: "field", Name: p.tokens[i].Text, StartLine: startLine, EndLine: endLine}
+		}
+		if p.tokens[i].Text != "]" && p.tokens[i].Text != "[" {
+			break
+		}
+	}
+	return nil
+}
+
+func (p *declarationParser) method(start, parenStart, startLine, endLine int) *Declaration {
+	nameIndex := parenStart - 1
+	if nameIndex < start || p.tokens[nameIndex].Kind != java.TokenIdentifier {
+		return nil
+	}
+	d := &Declaration{Kind: "method", Name: p.tokens[nameIndex].Text, StartLine: startLine, EndLine: endLine}
+	// 名称前面是修饰符、注解或泛型声明时为构造器
+	if prev := nameIndex - 1; prev < start || p.tokens[prev].Kind == java.TokenKeyword && !isPrimitive(p.tokens[prev].Text) ||
+		p.text(prev) == ">" && isTypeParameters(p.tokens[start:nameIndex]) || p.text(prev) == ")" {
+		d.Kind = "constructor"
+	}
+	d.Parameters = p.parameters(parenStart)
+	return d
+}
+
+func isPrimitive(s string) bool {
+	switch s {
+	case "boolean", "byte", "char", "short", "int", "long", "float", "double", "void":
+		return true
+	}
+	return false
+}
+
+// 判断 > 是否结束了方法自身的泛型声明（如 public <T> Foo(T t)），而不是返回类型的泛型参数
+func isTypeParameters(tokens []java.Token) bool {
+	depth := 0
+	for i, t := range tokens {
+		switch t.Text {
+		case "<":
+			if depth == 0 && i > 0 && tokens[i-1].Kind == java.TokenIdentifier {
+				return false
+			}
+			depth++
+		case ">":
+			depth--
+		}
+	}
+	return true
+}
+
+// 解析括号内的参数列表，返回每个参数的简单类型名
+func (p *declarationParser) parameters(parenStart int) []string {
+	var result []string
+	var current []java.Token
+	depth := 0
+	flush := func() {
+		if t := parameterType(current); t != "" {
+			result = append(result, t)
+		}
+		current = nil
+	}
+	for i := parenStart + 1; i < len(p.tokens); i++ {
+		t := p.tokens[i]
+		switch t.Text {
+		case "(", "<", "[":
+			depth++
+		case ")", ">", "]":
+			if t.Text == ")" && depth == 0 {
+				flush()
+				return result
+			}
+			depth--
+		case ",":
+			if depth == 0 {
+				flush()
+				continue
+			}
+		}
+		current = append(current, t)
+	}
+	return result
+}
+
+func parameterType(tokens []java.Token) string {
+	var parts []string
+	dims := 0
+	for i := 0; i < len(tokens); i++ {
+		t := tokens[i]
+		switch {
+		case t.Text == "@":
+			// 跳过注解名和参数
+			i++
+			for i+1 < len(tokens) && tokens[i+1].Text == "." {
+				i += 2
+			}
+			if i+1 < len(tokens) && tokens[i+1].Text == "(" {
+				depth := 0
+				for i++; i < len(tokens); i++ {
+					if tokens[i].Text == "(" {
+						depth++
+					} else if tokens[i].Text == ")" {
+						if depth--; depth == 0 {
+							break
+						}
+					}
+				}
+			}
+		case t.Text == "final":
+		case t.Text == "<":
+			// 去掉泛型参数
+			depth := 0
+			for ; i < len(tokens); i++ {
+				if tokens[i].Text == "<" {
+					depth++
+				} else if tokens[i].Text == ">" {
+					if depth--; depth == 0 {
+						break
+					}
+				}
+			}
+		case t.Text == "[" || t.Text == "...":
+			dims++
+		case t.Kind == java.TokenIdentifier || t.Kind == java.TokenKeyword:
+			parts = append(parts, t.Text)
+		}
+	}
+	// 最后一个标识符是参数名
+	if len(parts) < 2 {
+		return ""
+	}
+	return parts[len(parts)-2] + strings.Repeat("[]", dims)
+}
+
+func (p *declarationParser) parseClass(keyword string, startLine int) *Declaration {
+	p.i++ // class / interface / enum / record
+	d := &Declaration{Kind: "class", Name: p.text(p.i), StartLine: startLine}
+	for p.i < len(p.tokens) && p.text(p.i) != "{" {
+		if p.text(p.i) == "(" {
+			p.skipBalanced()
+			continue
+		}
+		p.i++
+	}
+	p.i++ // {
+	if keyword == "enum" {
+		p.skipEnumConstants()
+	}
+	for p.i < len(p.tokens) {
+		if p.text(p.i) == "}" {
+			d.EndLine = p.tokens[p.i].Line
+			p.i++
+			return d
+		}
+		if p.text(p.i) == ";" {
+			p.i++
+			continue
+		}
+		if child := p.parseMember(); child != nil {
+			d.Children = append(d.Children, child)
+		}
+	}
+	d.EndLine = p.tokens[len(p.tokens)-1].Line
+	return d
+}
+
+// 枚举常量到第一个顶层分号或类结尾为止，常量可能带参数和类体
+func (p *declarationParser) skipEnumConstants() {
+	for p.i < len(p.tokens) {
+		switch p.text(p.i) {
+		case ";":
+			p.i++
+			return
+		case "}":
+			return
+		case "(", "{", "[":
+			p.skipBalanced()
+		case "@":
+			p.skipAnnotation()
+		default:
+			p.i++
+		}
+	}
+}
+
+// CountLines 返回源码的行数，与 NumberLines 的行号一致
+func CountLines(content string) int {
+	return len(splitLines(content))
+}
+
+// NumberLines 返回 [start, end] 范围内带行号的源码，超出文件的部分被忽略
+func NumberLines(content string, start, end int) string {
+	lines := splitLines(content)
+	end = min(end, len(lines))
+	width := len(strconv.Itoa(end))
+	var out strings.Builder
+	for i := start; i <= end; i++ {
+		fmt.Fprintf(&out, "%*d  %s\n", width, i, lines[i-1])
+	}
+	return out.String()
+}
@@ -0,0 +1,150 @@
+package source
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"regexp"
+	"regexp/syntax"
+	"strings"
+)
+
+type ContextLine struct {
+	Line int    `json:"line"`
+	Text string `json:"text"`
+}
+
+type SearchHit struct {
+	File    string        `json:"file"`
+	Line    int           `json:"line"`
+	Context []ContextLine `json:"context"`
+}
+
+const contextLines = 2
+
+// Search 在源码目录中搜索，isRegex 为 false 时按字面量匹配
+func Search(folder, query string, isRegex, ignoreCase bool, maxCount int) ([]SearchHit, error) {
+	var words []string
+	var match func(line string) bool
+	if isRegex {
+		expr := query
+		if ignoreCase {
+			expr = "(?i)" + expr
+		}
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, err
+		}
+		literals, err := requiredLiterals(expr)
+		if err != nil {
+			return nil, err
+		}
+		for _, literal := range literals {
+			words = append(words, splitWords(literal)...)
+		}
+		match = re.MatchString
+	} else {
+		words = splitWords(query)
+		if ignoreCase {
+			lower := strings.ToLower(query)
+			match = func(line string) bool { return strings.Contains(strings.ToLower(line), lower) }
+		} else {
+			match = func(line string) bool { return strings.Contains(line, query) }
+		}
+	}
+	filtered := words[:0]
+	for _, word := range words {
+		if len(word) <= maxTokenLength {
+			filtered = append(filtered, word)
+		}
+	}
+
+	index, err := GetIndex(folder)
+	if err != nil {
+		return nil, err
+	}
+	candidates := index.Candidates(filtered)
+	files := make([]string, 0, len(candidates))
+	if candidates == nil {
+		files = index.Files
+	} else {
+		for _, id := range candidates {
+			files = append(files, index.Files[id])
+		}
+	}
+
+	hits := make([]SearchHit, 0)
+	for _, file := range files {
+		fileHits, err := searchFile(folder, file, match, maxCount-len(hits))
+		if err != nil {
+			return nil, err
+		}
+		hits = append(hits, fileHits...)
+		if len(hits) >= maxCount {
+			break
+		}
+	}
+	return hits, nil
+}
+
+func searchFile(folder, file string, match func(line string) bool, maxCount int) ([]SearchHit, error) {
+	f, err := os.Open(filepath.Join(folder, filepath.FromSlash(file)))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var lines []string
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	var hits []SearchHit
+	for i, line := range lines {
+		if len(hits) >= maxCount {
+			break
+		}
+		if !match(line) {
+			continue
+		}
+		hit := SearchHit{File: file, Line: i + 1}
+		for j := max(0, i-contextLines); j <= min(len(lines)-1, i+contextLines); j++ {
+			hit.Context = append(hit.Context, ContextLine{Line: j + 1, Text: lines[j]})
+		}
+		hits = append(hits, hit)
+	}
+	return hits, nil
+}
+
+// 从正则中提取所有匹配都必须包含的字面量，用于索引过滤
+func requiredLiterals(expr string) ([]string, error) {
+	re, err := syntax.Parse(expr, syntax.Perl)
+	if err != nil {
+		return nil, err
+	}
+	return collectLiterals(re.Simplify()), nil
+}
+
+func collectLiterals(re *syntax.Regexp) []string {
+	switch re.Op {
+	case syntax.OpLiteral:
+		return []string{string(re.Rune)}
+	case syntax.OpCapture, syntax.OpPlus:
+		return collectLiterals(re.Sub[0])
+	case syntax.OpRepeat:
+		if re.Min >= 1 {
+			return collectLiterals(re.Sub[0])
+		}
+	case syntax.OpConcat:
+		var literals []string
+		for _, sub := range re.Sub {
+			literals = append(literals, collectLiterals(sub)...)
+		}
+		return literals
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package source
+
+import (
+	"errors"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+type TreeEntry struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+	Type string `json:"type"` // package, class 或 file
+	Size int64  `json:"size,omitempty"`
+}
+
+var ErrOutsideFolder = errors.New("path is outside of the source folder")
+
+// ResolvePath 将相对路径拼接到源码目录下，拒绝跳出目录的路径
+func ResolvePath(folder, rel string) (string, error) {
+	full := filepath.Join(folder, filepath.FromSlash(cleanRel(rel)))
+	if full != filepath.Clean(folder) && !strings.HasPrefix(full, filepath.Clean(folder)+string(filepath.Separator)) {
+		return "", ErrOutsideFolder
+	}
+	return full, nil
+}
+
+// 清理相对路径中的 .. 和多余分隔符，结果不以 / 开头
+func cleanRel(rel string) string {
+	return strings.TrimPrefix(path.Clean("/"+strings.ReplaceAll(rel, "\\", "/")), "/")
+}
+
+// PackageToPath 将点分包名转为目录路径，已是目录路径时保持不变
+func PackageToPath(pkg string) string {
+	if strings.Contains(pkg, "/") {
+		return strings.Trim(pkg, "/")
+	}
+	return strings.ReplaceAll(pkg, ".", "/")
+}
+
+// ListTree 列出源码目录下某个包中的子包和类，子包在前
+func ListTree(folder, pkg string) ([]TreeEntry, error) {
+	rel := cleanRel(PackageToPath(pkg))
+	dir, err := ResolvePath(folder, rel)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]TreeEntry, 0, len(entries))
+	for _, entry := range entries {
+		item := TreeEntry{Name: entry.Name(), Path: path.Join(rel, entry.Name())}
+		if entry.IsDir() {
+			item.Type = "package"
+		} else {
+			info, err := entry.Info()
+			if err != nil {
+				return nil, err
+			}
+			item.Size = info.Size()
+			if strings.HasSuffix(item.Name, ".java") {
+				item.Type = "class"
+				item.Name = strings.TrimSuffix(item.Name, ".java")
+				item.Path = strings.TrimSuffix(item.Path, ".java")
+			} else {
+				item.Type = "file"
+			}
+		}
+		result = append(result, item)
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		if (result[i].Type == "package") != (result[j].Type == "package") {
+			return result[i].Type == "package"
+		}
+		return result[i].Name < result[j].Name
+	})
+	return result, nil
+}
@@ -0,0 +1,348 @@
+package source
+
+import (
+	"encoding/gob"
+	"errors"
+	"log/slog"
+	"os"
+	"pluto/classfile"
+	"strings"
+	"sync"
+	"time"
+)
+
+// XrefIndex 从重映射后的 jar 字节码中提取的声明与引用，字符串均存放在 Strings 中以节省空间
+type XrefIndex struct {
+	Strings      []string
+	Parents      map[int32][]int32 // 类 -> 父类和接口
+	Declarations []XrefEntry
+	References   []XrefEntry
+}
+
+// XrefEntry 某个符号出现在哪个类的哪个成员中，Name 为 -1 表示符号是类本身
+type XrefEntry struct {
+	Owner      int32
+	Name       int32
+	Descriptor int32
+	InClass    int32
+	InMember   int32 // 所在方法的名称加描述符，-1 表示类级别
+	Line       int32
+}
+
+type XrefLocation struct {
+	Symbol string `json:"symbol"`
+	Class  string `json:"class"`
+	File   string `json:"file"` // 对应的源码类路径，可直接用于 /api/source/get
+	Member string `json:"member,omitempty"`
+	Line   int    `json:"line,omitempty"` // 字节码中的行号
+}
+
+type XrefResult struct {
+	Declarations []XrefLocation `json:"declarations"`
+	References   []XrefLocation `json:"references"`
+}
+
+var (
+	xrefIndexes     = map[string]*XrefIndex{}
+	xrefIndexesLock sync.Mutex
+)
+
+func xrefPath(jarPath string) string {
+	return jarPath + ".xref"
+}
+
+type xrefBuilder struct {
+	index   *XrefIndex
+	strings map[string]int32
+}
+
+func (b *xrefBuilder) intern(s string) int32 {
+	if id, ok := b.strings[s]; ok {
+		return id
+	}
+	id := int32(len(b.index.Strings))
+	b.index.Strings = append(b.index.Strings, s)
+	b.strings[s] = id
+	return id
+}
+
+func (b *xrefBuilder) class(internalName string) int32 {
+	return b.intern(strings.ReplaceAll(internalName, "/", "."))
+}
+
+// BuildXref 解析 jar 中所有类生成交叉引用索引并保存到 jar 旁的 .xref 文件
+func BuildXref(jarPath string) (*XrefIndex, error) {
+	start := time.Now()
+	slog.Info("Building cross reference index for " + jarPath)
+	b := &xrefBuilder{
+		index:   &XrefIndex{Parents: make(map[int32][]int32)},
+		strings: make(map[string]int32),
+	}
+	err := classfile.WalkJar(jarPath, func(cf *classfile.ClassFile) error {
+		return b.addClass(cf)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := saveGob(xrefPath(jarPath), b.index); err != nil {
+		slog.Error("Failed to save cross reference index: " + err.Error())
+	}
+	xrefIndexesLock.Lock()
+	xrefIndexes[jarPath] = b.index
+	xrefIndexesLock.Unlock()
+	slog.Info("Indexed " + jarPath + " in " + time.Since(start).String())
+	return b.index, nil
+}
+
+func (b *xrefBuilder) addClass(cf *classfile.ClassFile) error {
+	this := b.class(cf.ThisClass)
+	if cf.SuperClass != "" {
+		b.index.Parents[this] = append(b.index.Parents[this], b.class(cf.SuperClass))
+	}
+	for _, i := range cf.Interfaces {
+		b.index.Parents[this] = append(b.index.Parents[this], b.class(i))
+	}
+	b.index.Declarations = append(b.index.Declarations, XrefEntry{Owner: this, Name: -1, Descriptor: -1, InClass: this, InMember: -1})
+	for _, field := range cf.Fields {
+		b.index.Declarations = append(b.index.Declarations, XrefEntry{
+			Owner: this, Name: b.intern(field.Name), Descriptor: b.intern(field.Descriptor), InClass: this, InMember: -1,
+		})
+	}
+	bootstrap := cf.BootstrapMethods()
+	for i := range cf.Methods {
+		method := &cf.Methods[i]
+		inMember := b.intern(method.Name + method.Descriptor)
+		code, err := method.ParseCode(cf.ConstantPool)
+		if err != nil {
+			return err
+		}
+		declaration := XrefEntry{Owner: this, Name: b.intern(method.Name), Descriptor: b.intern(method.Descriptor), InClass: this, InMember: -1}
+		if code == nil {
+			b.index.Declarations = append(b.index.Declarations, declaration)
+			continue
+		}
+		lines := code.LineNumbers()
+		if len(lines) > 0 {
+			declaration.Line = int32(lines[0].Line)
+			for _, line := range lines {
+				declaration.Line = min(declaration.Line, int32(line.Line))
+			}
+		}
+		b.index.Declarations = append(b.index.Declarations, declaration)
+		instructions, err := code.Instructions()
+		if err != nil {
+			return err
+		}
+		for _, ins := range instructions {
+			line := int32(classfile.LineAt(lines, ins.Offset))
+			for _, ref := range referencesOf(cf, bootstrap, ins) {
+				entry := XrefEntry{Owner: b.class(ref[0]), Name: -1, Descriptor: -1, InClass: this, InMember: inMember, Line: line}
+				if ref[1] != "" {
+					entry.Name, entry.Descriptor = b.intern(ref[1]), b.intern(ref[2])
+				}
+				b.index.References = append(b.index.References, entry)
+			}
+		}
+	}
+	return nil
+}
+
+// 返回指令引用的类或成员，每项为 [所属类, 名称, 描述符]，类引用的名称为空
+func referencesOf(cf *classfile.ClassFile, bootstrap []classfile.BootstrapMethod, ins classfile.Instruction) [][3]string {
+	pool := cf.ConstantPool
+	index := uint16(ins.Index)
+	switch ins.Opcode {
+	case classfile.OpGetStatic, classfile.OpPutStatic, classfile.OpGetField, classfile.OpPutField,
+		classfile.OpInvokeVirtual, classfile.OpInvokeSpecial, classfile.OpInvokeStatic, classfile.OpInvokeInterface:
+		owner, name, descriptor := pool.MemberRef(index)
+		return [][3]string{{owner, name, descriptor}}
+	case classfile.OpNew, classfile.OpANewArray, classfile.OpCheckCast, classfile.OpInstanceOf, classfile.OpMultiANewArray:
+		return classRef(pool.ClassName(index))
+	case classfile.OpLdc, classfile.OpLdcW:
+		if pool.Tag(index) == classfile.TagClass {
+			return classRef(pool.ClassName(index))
+		}
+	case classfile.OpInvokeDynamic:
+		// lambda 和方法引用通过 bootstrap 参数中的 MethodHandle 指向实际方法
+		constant := pool.Get(index)
+		if constant == nil || int(constant.Ref1) >= len(bootstrap) {
+			return nil
+		}
+		var refs [][3]string
+		for _, arg := range bootstrap[constant.Ref1].Arguments {
+			if handle := pool.Get(arg); handle != nil && handle.Tag == classfile.TagMethodHandle {
+				owner, name, descriptor := pool.MemberRef(handle.Ref1)
+				refs = append(refs, [3]string{owner, name, descriptor})
+			}
+		}
+		return refs
+	}
+	return nil
+}
+
+// 数组类型引用只记录元素类型
+func classRef(name string) [][3]string {
+	name = strings.TrimLeft(name, "[")
+	if strings.HasPrefix(name, "L") && strings.HasSuffix(name, ";") {
+		name = name[1 : len(name)-1]
+	} else if len(name) == 1 {
+		return nil
+	}
+	return [][3]string{{name, "", ""}}
+}
+
+// GetXref 依次从内存、磁盘获取索引，都没有时重新构建
+func GetXref(jarPath string) (*XrefIndex, error) {
+	xrefIndexesLock.Lock()
+	index, ok := xrefIndexes[jarPath]
+	xrefIndexesLock.Unlock()
+	if ok {
+		return index, nil
+	}
+	index = &XrefIndex{}
+	err := loadGob(xrefPath(jarPath), index)
+	if err == nil {
+		xrefIndexesLock.Lock()
+		xrefIndexes[jarPath] = index
+		xrefIndexesLock.Unlock()
+		return index, nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		slog.Warn("Failed to load cross reference index, rebuilding: " + err.Error())
+	}
+	return BuildXref(jarPath)
+}
+
+// Query 查询符号的声明和引用，符号格式为 类、类#成员 或 类#成员描述符，类可以是简单名，
+// 对成员的引用也包括通过子类发起的调用
+func (x *XrefIndex) Query(symbol string, maxCount int) XrefResult {
+	classPart, member, descriptor := parseSymbol(symbol)
+	owners := x.findClasses(classPart)
+	// 兼容 Entity.discard 这种写法
+	if len(owners) == 0 && member == "" {
+		if idx := strings.LastIndexByte(classPart, '.'); idx > 0 {
+			classPart, member = classPart[:idx], classPart[idx+1:]
+			owners = x.findClasses(classPart)
+		}
+	}
+	if member != "" {
+		owners = x.withSubclasses(owners)
+	}
+	match := func(entry XrefEntry) bool {
+		if _, ok := owners[entry.Owner]; !ok {
+			return false
+		}
+		if member == "" {
+			return entry.Name == -1
+		}
+		return entry.Name >= 0 && x.Strings[entry.Name] == member &&
+			(descriptor == "" || x.Strings[entry.Descriptor] == descriptor)
+	}
+	result := XrefResult{Declarations: []XrefLocation{}, References: []XrefLocation{}}
+	for _, entry := range x.Declarations {
+		if match(entry) && len(result.Declarations) < maxCount {
+			result.Declarations = append(result.Declarations, x.location(entry))
+		}
+	}
+	for _, entry := range x.References {
+		if match(entry) && len(result.References) < maxCount {
+			result.References = append(result.References, x.location(entry))
+		}
+	}
+	return result
+}
+
+// 按全名或简单名查找 jar 中的类
+func (x *XrefIndex) findClasses(name string) map[int32]struct{} {
+	owners := make(map[int32]struct{})
+	for id, s := range x.Strings {
+		if s == name || strings.HasSuffix(s, "."+name) || strings.HasSuffix(s, "$"+name) {
+			if _, ok := x.Parents[int32(id)]; ok {
+				owners[int32(id)] = struct{}{}
+			}
+		}
+	}
+	return owners
+}
+
+func parseSymbol(symbol string) (string, string, string) {
+	symbol = strings.ReplaceAll(symbol, "/", ".")
+	classPart, member, found := strings.Cut(symbol, "#")
+	if !found {
+		return classPart, "", ""
+	}
+	if idx := strings.IndexAny(member, "(:"); idx >= 0 {
+		return classPart, member[:idx], strings.TrimPrefix(member[idx:], ":")
+	}
+	return classPart, member, ""
+}
+
+// 补充所有直接或间接继承自 owners 的类
+func (x *XrefIndex) withSubclasses(owners map[int32]struct{}) map[int32]struct{} {
+	children := make(map[int32][]int32)
+	for class, parents := range x.Parents {
+		for _, parent := range parents {
+			children[parent] = append(children[parent], class)
+		}
+	}
+	result := make(map[int32]struct{}, len(owners))
+	queue := make([]int32, 0, len(owners))
+	for owner := range owners {
+		result[owner] = struct{}{}
+		queue = append(queue, owner)
+	}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, child := range children[current] {
+			if _, ok := result[child]; !ok {
+				result[child] = struct{}{}
+				queue = append(queue, child)
+			}
+		}
+	}
+	return result
+}
+
+func (x *XrefIndex) location(entry XrefEntry) XrefLocation {
+	owner := x.Strings[entry.Owner]
+	location := XrefLocation{
+		Symbol: owner,
+		Class:  x.Strings[entry.InClass],
+		File:   ClassFilePath(x.Strings[entry.InClass]),
+		Line:   int(entry.Line),
+	}
+	if entry.Name >= 0 {
+		location.Symbol += "#" + x.Strings[entry.Name] + x.Strings[entry.Descriptor]
+	}
+	if entry.InMember >= 0 {
+		location.Member = x.Strings[entry.InMember]
+	}
+	return location
+}
+
+// ClassFilePath 将点分类名转为源码类路径，内部类对应外部类的文件
+func ClassFilePath(class string) string {
+	if idx := strings.IndexByte(class, '$'); idx > 0 {
+		class = class[:idx]
+	}
+	return strings.ReplaceAll(class, ".", "/")
+}
+
+func saveGob(path string, value any) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return gob.NewEncoder(file).Encode(value)
+}
+
+func loadGob(path string, value any) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return gob.NewDecoder(file).Decode(value)
+}
@@ -2,21 +2,94 @@ package util
 
 import (
 	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"golang.org/x/text/encoding"
 	"golang.org/x/text/encoding/simplifiedchinese"
 	"golang.org/x/text/transform"
 	"io"
 	"log/slog"
 	"os/exec"
+	"runtime"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
-func convertGBKToUTF8(r io.Reader) io.Reader {
-	return transform.NewReader(r, simplifiedchinese.GBK.NewDecoder())
+// 外部程序输出的编码，为 nil 时按 UTF-8 处理
+var outputEncoding encoding.Encoding
+
+// SetCommandEncoding 设置外部程序输出的编码，支持 utf-8 和 gbk，auto 时在 Windows 上为 gbk，否则为 utf-8
+func SetCommandEncoding(name string) error {
+	switch strings.ToLower(name) {
+	case "", "auto":
+		if runtime.GOOS == "windows" {
+			outputEncoding = simplifiedchinese.GBK
+		} else {
+			outputEncoding = nil
+		}
+	case "utf-8", "utf8":
+		outputEncoding = nil
+	case "gbk":
+		outputEncoding = simplifiedchinese.GBK
+	default:
+		return errors.New("unknown command encoding " + name)
+	}
+	return nil
+}
+
+func decodeOutput(r io.Reader) io.Reader {
+	if outputEncoding == nil {
+		return r
+	}
+	return transform.NewReader(r, outputEncoding.NewDecoder())
+}
+
+type commandLogKey struct{}
+
+// WithCommandLog 返回携带日志的 context，RunCommand 会把命令、全部输出和退出状态逐行交给 f
+func WithCommandLog(ctx context.Context, f func(line string)) context.Context {
+	return context.WithValue(ctx, commandLogKey{}, f)
+}
+
+func commandLog(ctx context.Context, line string) {
+	if f, ok := ctx.Value(commandLogKey{}).(func(line string)); ok {
+		f(line)
+	}
+}
+
+// CommandOptions 外部程序的执行选项
+type CommandOptions struct {
+	PrintErrorOnly bool              // 只在日志中输出包含 ERROR 的标准输出
+	OnLine         func(line string) // 标准输出和标准错误的每一行，会在不同的 goroutine 中调用
+	Timeout        time.Duration     // 运行时间上限，超时后杀死程序并返回错误，0 表示不限制
 }
 
-func ExecuteCommand(command string, args []string, printErrorOnly bool) error {
+// 取消后等待输出关闭的最长时间
+const cancelWaitDelay = 5 * time.Second
+
+// RunCommand 执行外部程序并等待其结束，ctx 取消时杀死程序及其子进程并返回 ctx.Err()，
+// 参数中的 -Xmx 计入内存预算，预算不足时等待其它程序结束，超时和超出资源限制时返回错误
+func RunCommand(ctx context.Context, command string, args []string, options CommandOptions) error {
+	release, err := acquireMemory(ctx, ParseJavaMemory(args))
+	if err != nil {
+		commandLog(ctx, "Not started: "+err.Error())
+		return err
+	}
+	defer release()
+	runCtx := ctx
+	if options.Timeout > 0 {
+		var cancel context.CancelFunc
+		runCtx, cancel = context.WithTimeout(ctx, options.Timeout)
+		defer cancel()
+	}
 	slog.Info("Executing command: " + command + " " + strings.Join(args, " "))
-	cmd := exec.Command(command, args...)
+	commandLog(ctx, "$ "+command+" "+strings.Join(args, " "))
+	cmd := exec.CommandContext(runCtx, command, args...)
+	setProcessGroup(cmd)
+	cmd.WaitDelay = cancelWaitDelay
 	stderr, err := cmd.StderrPipe()
 	if err != nil {
 		return err
@@ -28,21 +101,61 @@ func ExecuteCommand(command string, args []string, printErrorOnly bool) error {
 	if err := cmd.Start(); err != nil {
 		return err
 	}
+	if err := setResourceLimits(cmd.Process.Pid); err != nil {
+		slog.Warn("Failed to set resource limits: " + err.Error())
+	}
+	var outOfMemory atomic.Bool
+	scan := func(text string) {
+		if strings.Contains(text, "java.lang.OutOfMemoryError") {
+			outOfMemory.Store(true)
+		}
+		if options.OnLine != nil {
+			options.OnLine(text)
+		}
+	}
+	var wg sync.WaitGroup
+	wg.Add(2)
 	go func() {
-		scanner := bufio.NewScanner(convertGBKToUTF8(stderr))
+		defer wg.Done()
+		scanner := bufio.NewScanner(decodeOutput(stderr))
 		for scanner.Scan() {
-			slog.Debug(scanner.Text())
+			text := scanner.Text()
+			slog.Debug(text)
+			commandLog(ctx, "[stderr] "+text)
+			scan(text)
 		}
 	}()
 	go func() {
-		scanner := bufio.NewScanner(convertGBKToUTF8(stdout))
+		defer wg.Done()
+		scanner := bufio.NewScanner(decodeOutput(stdout))
 		for scanner.Scan() {
 			text := scanner.Text()
-			if !printErrorOnly || strings.Contains(text, "ERROR") {
+			if !options.PrintErrorOnly || strings.Contains(text, "ERROR") {
 				slog.Debug(text)
 			}
+			commandLog(ctx, "[stdout] "+text)
+			scan(text)
 		}
 	}()
+	// 读完输出后才能调用 Wait
+	wg.Wait()
 	err = cmd.Wait()
+	if ctx.Err() != nil {
+		commandLog(ctx, "Killed: "+ctx.Err().Error())
+		return ctx.Err()
+	}
+	switch {
+	case runCtx.Err() != nil:
+		err = fmt.Errorf("%w after %s", ErrTimeout, options.Timeout)
+	case err != nil && outOfMemory.Load():
+		err = fmt.Errorf("%w: %w", ErrOutOfMemory, err)
+	case err != nil:
+		err = describeExit(err)
+	}
+	if err != nil {
+		commandLog(ctx, "Exited: "+err.Error())
+	} else {
+		commandLog(ctx, "Exited: 0")
+	}
 	return err
 }
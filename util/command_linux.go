@@ -0,0 +1,49 @@
+package util
+
+import (
+	"errors"
+	"fmt"
+	"golang.org/x/sys/unix"
+	"os/exec"
+	"syscall"
+)
+
+// CPU 时间超出软限制后收到 SIGXCPU，仍未退出时在硬限制处被杀死
+const cpuHardLimitGrace = 5
+
+// 为已启动的程序设置 CPU 时间和文件打开数限制，Go 不支持在 exec 前设置，但 JVM 启动需要时间，影响不大
+func setResourceLimits(pid int) error {
+	limits := getProcessLimits()
+	if seconds := uint64(limits.CpuTime.Seconds()); seconds > 0 {
+		limit := unix.Rlimit{Cur: seconds, Max: seconds + cpuHardLimitGrace}
+		if err := unix.Prlimit(pid, unix.RLIMIT_CPU, &limit, nil); err != nil {
+			return err
+		}
+	}
+	if limits.OpenFiles > 0 {
+		limit := unix.Rlimit{Cur: limits.OpenFiles, Max: limits.OpenFiles}
+		if err := unix.Prlimit(pid, unix.RLIMIT_NOFILE, &limit, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 将因超出 CPU 时间被杀死的退出状态转换为可读的错误
+func describeExit(err error) error {
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		return err
+	}
+	status, ok := exitErr.Sys().(syscall.WaitStatus)
+	if ok && status.Signaled() && (status.Signal() == syscall.SIGXCPU || status.Signal() == syscall.SIGKILL && exceededCpuTime(exitErr)) {
+		return fmt.Errorf("%w of %s", ErrCpuTime, getProcessLimits().CpuTime)
+	}
+	return err
+}
+
+// 被 SIGKILL 杀死时根据已用 CPU 时间判断是否是硬限制导致的
+func exceededCpuTime(exitErr *exec.ExitError) bool {
+	limit := getProcessLimits().CpuTime
+	return limit > 0 && exitErr.UserTime()+exitErr.SystemTime() >= limit
+}
@@ -0,0 +1,12 @@
+//go:build !linux
+
+package util
+
+// 只有 Linux 支持为其它进程设置资源限制
+func setResourceLimits(pid int) error {
+	return nil
+}
+
+func describeExit(err error) error {
+	return err
+}
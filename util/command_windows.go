@@ -0,0 +1,15 @@
+//go:build windows
+
+package util
+
+import (
+	"os/exec"
+	"strconv"
+)
+
+// 取消时用 taskkill 结束整个进程树
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.Cancel = func() error {
+		return exec.Command("taskkill", "/T", "/F", "/PID", strconv.Itoa(cmd.Process.Pid)).Run()
+	}
+}
@@ -3,6 +3,7 @@ package util
 import (
 	"encoding/json"
 	"os"
+	"path/filepath"
 )
 
 func LoadConfig[T any](path string) (T, error) {
@@ -20,10 +21,31 @@ func LoadConfig[T any](path string) (T, error) {
 	return config, nil
 }
 
+// SaveConfig 先写入同目录下的临时文件再替换，写入中途崩溃时原文件保持完整
 func SaveConfig(config any, path string) error {
 	data, err := json.MarshalIndent(config, "", "  ")
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(path, data, 0644)
+	file, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tempPath := file.Name()
+	defer os.Remove(tempPath)
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tempPath, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tempPath, path)
 }
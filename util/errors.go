@@ -0,0 +1,44 @@
+package util
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"os/exec"
+)
+
+// 错误类别，用于决定失败的任务是否重试
+const (
+	ErrorNetwork = "network" // 连接失败、超时或服务器暂时不可用
+	ErrorTimeout = "timeout" // 外部程序运行超时或超出 CPU 时间
+	ErrorMemory  = "memory"  // Java 内存不足
+	ErrorProcess = "process" // 外部程序异常退出
+)
+
+var (
+	ErrTimeout     = errors.New("timed out")
+	ErrOutOfMemory = errors.New("java ran out of memory")
+)
+
+// ErrorClass 返回错误的类别，无法归类的错误（如版本不存在）返回空字符串，视为永久错误
+func ErrorClass(err error) string {
+	var netErr net.Error
+	var temporary interface{ Temporary() bool }
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil, errors.Is(err, context.Canceled):
+		return ""
+	case errors.Is(err, ErrTimeout), errors.Is(err, ErrCpuTime):
+		return ErrorTimeout
+	case errors.Is(err, ErrOutOfMemory):
+		return ErrorMemory
+	case errors.As(err, &netErr), errors.Is(err, io.ErrUnexpectedEOF):
+		return ErrorNetwork
+	case errors.As(err, &temporary) && temporary.Temporary():
+		return ErrorNetwork
+	case errors.As(err, &exitErr):
+		return ErrorProcess
+	}
+	return ""
+}
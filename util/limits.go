@@ -0,0 +1,197 @@
+package util
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrCpuTime 程序因超出 CPU 时间限制被杀死
+var ErrCpuTime = errors.New("exceeded the CPU time limit")
+
+// ProcessLimits 外部程序的资源限制，为 0 的项不限制
+type ProcessLimits struct {
+	// 同时运行的 Java 程序按 -Xmx 计算的内存总和上限，字节，0 表示按占用时系统的可用内存计算，小于 0 表示不限制
+	MemoryBudget int64
+	CpuTime      time.Duration // 每个程序的 CPU 时间，仅 Linux
+	OpenFiles    uint64        // 每个程序能打开的文件数，仅 Linux
+}
+
+var (
+	// processLimits 和 memoryUsed 都由 memoryLock 保护
+	processLimits ProcessLimits
+	memoryLock    sync.Mutex
+	memoryUsed    int64
+	memoryFreed   = make(chan struct{}) // 释放内存时关闭并替换，用于唤醒等待的程序
+)
+
+func SetProcessLimits(limits ProcessLimits) {
+	memoryLock.Lock()
+	defer memoryLock.Unlock()
+	processLimits = limits
+}
+
+func getProcessLimits() ProcessLimits {
+	memoryLock.Lock()
+	defer memoryLock.Unlock()
+	return processLimits
+}
+
+type reservedMemoryKey struct{}
+
+// WithReservedMemory 返回携带已预留内存的 context，RunCommand 不再为不超过 size 的程序占用内存预算
+func WithReservedMemory(ctx context.Context, size int64) context.Context {
+	return context.WithValue(ctx, reservedMemoryKey{}, size)
+}
+
+func reservedMemory(ctx context.Context) int64 {
+	size, _ := ctx.Value(reservedMemoryKey{}).(int64)
+	return size
+}
+
+// CheckMemory 检查 size 字节是否可能在固定的内存预算内运行，超过预算总量时返回错误
+func CheckMemory(size int64) error {
+	budget := getProcessLimits().MemoryBudget
+	if budget > 0 && size > budget {
+		return fmt.Errorf("needs %dMB of memory but the memory budget is %dMB", size>>20, budget>>20)
+	}
+	return nil
+}
+
+// 判断现在能否占用 size 字节，需要持有 memoryLock，
+// 按可用内存计算时预算为已占用的加上系统当前的可用内存，没有程序占用时总是允许，避免永远等待
+func fitsMemoryLocked(size int64) bool {
+	budget := processLimits.MemoryBudget
+	if budget < 0 || size <= 0 {
+		return true
+	}
+	if budget == 0 {
+		available := AvailableMemory()
+		if available <= 0 {
+			return true
+		}
+		budget = memoryUsed + available
+		if memoryUsed == 0 {
+			return true
+		}
+	}
+	return memoryUsed+size <= budget
+}
+
+func fitsMemory(size int64) bool {
+	memoryLock.Lock()
+	defer memoryLock.Unlock()
+	return fitsMemoryLocked(size)
+}
+
+// 在内存足够时占用 size 字节，不等待
+func tryReserveMemory(size int64) bool {
+	memoryLock.Lock()
+	defer memoryLock.Unlock()
+	if !fitsMemoryLocked(size) {
+		return false
+	}
+	memoryUsed += size
+	return true
+}
+
+// 释放占用的内存，外部程序都在线程池的任务中运行，任务结束时线程池会重新检查等待内存的任务
+func releaseMemory(size int64) {
+	if size <= 0 {
+		return
+	}
+	memoryLock.Lock()
+	memoryUsed -= size
+	close(memoryFreed)
+	memoryFreed = make(chan struct{})
+	memoryLock.Unlock()
+}
+
+// 等待内存预算足够后占用 size 字节，超过预算总量时直接返回错误，ctx 中已预留足够内存时不再占用
+func acquireMemory(ctx context.Context, size int64) (func(), error) {
+	if size <= reservedMemory(ctx) {
+		return func() {}, nil
+	}
+	if err := CheckMemory(size); err != nil {
+		return nil, err
+	}
+	memoryLock.Lock()
+	if !fitsMemoryLocked(size) {
+		commandLog(ctx, fmt.Sprintf("Waiting for %dMB of memory, %dMB in use", size>>20, memoryUsed>>20))
+	}
+	for !fitsMemoryLocked(size) {
+		freed := memoryFreed
+		memoryLock.Unlock()
+		select {
+		case <-freed:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		memoryLock.Lock()
+	}
+	memoryUsed += size
+	memoryLock.Unlock()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			releaseMemory(size)
+		})
+	}, nil
+}
+
+// ParseJavaMemory 返回 Java 参数中 -Xmx 指定的最大堆内存，字节，没有指定时返回 0
+func ParseJavaMemory(args []string) int64 {
+	var result int64
+	for _, arg := range args {
+		value, ok := strings.CutPrefix(arg, "-Xmx")
+		if !ok || value == "" {
+			continue
+		}
+		unit := int64(1)
+		switch value[len(value)-1] {
+		case 'k', 'K':
+			unit = 1 << 10
+		case 'm', 'M':
+			unit = 1 << 20
+		case 'g', 'G':
+			unit = 1 << 30
+		case 't', 'T':
+			unit = 1 << 40
+		}
+		if unit != 1 {
+			value = value[:len(value)-1]
+		}
+		if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+			// 以最后一个为准，与 JVM 相同
+			result = n * unit
+		}
+	}
+	return result
+}
+
+// AvailableMemory 返回系统当前可用的内存，字节，无法获取时（非 Linux）返回 0
+func AvailableMemory() int64 {
+	file, err := os.Open("/proc/meminfo")
+	if err != nil {
+		return 0
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 2 && fields[0] == "MemAvailable:" {
+			kb, err := strconv.ParseInt(fields[1], 10, 64)
+			if err != nil {
+				return 0
+			}
+			return kb << 10
+		}
+	}
+	return 0
+}
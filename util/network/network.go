@@ -1,7 +1,7 @@
 package network
 
 import (
-	"errors"
+	"context"
 	"fmt"
 	"io"
 	"log/slog"
@@ -12,6 +12,20 @@ import (
 	"time"
 )
 
+// StatusError 服务器返回了 200 以外的状态码
+type StatusError struct {
+	Code int
+}
+
+func (e *StatusError) Error() string {
+	return strconv.Itoa(e.Code)
+}
+
+// Temporary 服务器错误和请求过多时可以稍后重试
+func (e *StatusError) Temporary() bool {
+	return e.Code >= 500 || e.Code == http.StatusTooManyRequests || e.Code == http.StatusRequestTimeout
+}
+
 func Get(url string) ([]byte, error) {
 	slog.Info("Downloading: " + url)
 	client := &http.Client{Timeout: 30 * time.Second}
@@ -25,19 +39,24 @@ func Get(url string) ([]byte, error) {
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode != http.StatusOK {
-		return nil, errors.New(strconv.Itoa(resp.StatusCode))
+		return nil, &StatusError{Code: resp.StatusCode}
 	}
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("读取 Yarn 版本列表响应失败: %v", err)
+		return nil, fmt.Errorf("读取 Yarn 版本列表响应失败: %w", err)
 	}
 	return body, nil
 }
 
 func File(url string, path string) error {
+	return FileContext(context.Background(), url, path)
+}
+
+// FileContext 下载文件到 path，下载进度通过 util.ReportProgress 报告
+func FileContext(ctx context.Context, url string, path string) error {
 	slog.Info("Downloading: " + url)
 	client := &http.Client{Timeout: 30 * time.Second}
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return err
 	}
@@ -47,7 +66,7 @@ func File(url string, path string) error {
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode != http.StatusOK {
-		return errors.New(strconv.Itoa(resp.StatusCode))
+		return &StatusError{Code: resp.StatusCode}
 	}
 
 	// 创建临时文件
@@ -61,8 +80,7 @@ func File(url string, path string) error {
 		os.Remove(tempPath)
 	}()
 
-	file, err := os.Create(tempPath)
-	_, err = io.Copy(file, resp.Body)
+	_, err = io.Copy(tempFile, &progressReader{ctx: ctx, reader: resp.Body, total: resp.ContentLength, name: path})
 	if err != nil {
 		return err
 	}
@@ -79,3 +97,25 @@ func File(url string, path string) error {
 	}
 	return err
 }
+
+// 报告进度的最小间隔
+const progressInterval = 200 * time.Millisecond
+
+type progressReader struct {
+	ctx      context.Context
+	reader   io.Reader
+	read     int64
+	total    int64 // 未知时为 -1
+	name     string
+	reported time.Time
+}
+
+func (r *progressReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.read += int64(n)
+	if now := time.Now(); err != nil || now.Sub(r.reported) >= progressInterval {
+		r.reported = now
+		util.ReportProgress(r.ctx, r.read, r.total, r.name)
+	}
+	return n, err
+}
@@ -0,0 +1,20 @@
+package util
+
+import "context"
+
+// ProgressFunc 接收长时间操作的进度，total 未知时为 -1
+type ProgressFunc func(current, total int64, message string)
+
+type progressKey struct{}
+
+// WithProgress 返回携带进度回调的 context，下载和外部程序会通过它报告进度
+func WithProgress(ctx context.Context, f ProgressFunc) context.Context {
+	return context.WithValue(ctx, progressKey{}, f)
+}
+
+// ReportProgress 报告进度，context 中没有回调时忽略
+func ReportProgress(ctx context.Context, current, total int64, message string) {
+	if f, ok := ctx.Value(progressKey{}).(ProgressFunc); ok {
+		f(current, total, message)
+	}
+}
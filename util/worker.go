@@ -1,22 +1,90 @@
 package util
 
 import (
+	"context"
+	"errors"
 	"log/slog"
 	"sync"
 )
 
+type Priority int
+
+const (
+	PriorityLow    Priority = iota // 批量预热等后台任务
+	PriorityNormal                 // 用户提交的任务
+	PriorityHigh                   // 有请求在等待结果的任务，如单个类的反编译
+)
+
+var (
+	ErrQueueFull       = errors.New("queue is full")
+	ErrUnknownPriority = errors.New("unknown priority, should be low, normal or high")
+	priorityNames      = []string{"low", "normal", "high"}
+)
+
+func (p Priority) String() string {
+	if p < 0 || int(p) >= len(priorityNames) {
+		return "unknown"
+	}
+	return priorityNames[p]
+}
+
+func (p Priority) MarshalText() ([]byte, error) {
+	return []byte(p.String()), nil
+}
+
+func (p *Priority) UnmarshalText(text []byte) error {
+	parsed, err := ParsePriority(string(text))
+	if err != nil {
+		return err
+	}
+	*p = parsed
+	return nil
+}
+
+// ParsePriority 解析 low、normal 或 high，空字符串为 normal
+func ParsePriority(s string) (Priority, error) {
+	if s == "" {
+		return PriorityNormal, nil
+	}
+	for i, name := range priorityNames {
+		if name == s {
+			return Priority(i), nil
+		}
+	}
+	return PriorityNormal, ErrUnknownPriority
+}
+
+// Task 线程池中的任务，同一 Kind 同时运行的数量受限制
+type Task struct {
+	Kind     string
+	Priority Priority
+	// Memory 任务中 Java 程序按 -Xmx 计算的最大内存，字节，内存预算足够时才开始运行，
+	// Run 中需要通过 WithReservedMemory 告知 RunCommand
+	Memory int64
+	Run    func() error
+}
+
 type WorkerPool struct {
-	taskChan chan func() error
-	wg       sync.WaitGroup
-	once     sync.Once
+	mu          sync.Mutex
+	cond        *sync.Cond
+	queue       []Task // 按提交顺序排列，取出时选择优先级最高且未达到数量限制的
+	running     map[string]int
+	limits      map[string]int
+	queueLength int
+	closed      bool
+	wg          sync.WaitGroup
 }
 
-var threadPool = getWorkerPool(4)
+var threadPool = getWorkerPool(4, 100, nil)
 
-func getWorkerPool(numWorkers int) *WorkerPool {
+// getWorkerPool limits 为每种任务同时运行的最大数量，没有配置或不大于 0 时只受线程数限制
+func getWorkerPool(numWorkers, queueLength int, limits map[string]int) *WorkerPool {
 	pool := &WorkerPool{
-		taskChan: make(chan func() error, 100),
+		running:     make(map[string]int),
+		limits:      limits,
+		queueLength: queueLength,
 	}
+	pool.cond = sync.NewCond(&pool.mu)
 	pool.wg.Add(numWorkers)
 	for i := 0; i < numWorkers; i++ {
 		go pool.worker()
@@ -26,30 +94,119 @@ func getWorkerPool(numWorkers int) *WorkerPool {
 
 func (p *WorkerPool) worker() {
 	defer p.wg.Done()
-	for task := range p.taskChan {
-		if task != nil {
-			if err := task(); err != nil {
-				slog.Error("Function task failed: " + err.Error())
+	for {
+		task, ok := p.take()
+		if !ok {
+			return
+		}
+		if err := task.Run(); err != nil {
+			slog.Error("Function task failed: " + err.Error())
+		}
+		p.mu.Lock()
+		p.running[task.Kind]--
+		p.mu.Unlock()
+		releaseMemory(task.Memory)
+		// 同类任务的名额和内存空出来了
+		p.cond.Broadcast()
+	}
+}
+
+// 等待可以运行的任务，线程池关闭且队列为空时返回 false
+func (p *WorkerPool) take() (Task, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for {
+		best := -1
+		for i, task := range p.queue {
+			if limit := p.limits[task.Kind]; limit > 0 && p.running[task.Kind] >= limit {
+				continue
+			}
+			if best >= 0 && task.Priority <= p.queue[best].Priority {
+				continue
+			}
+			// 内存不足时先运行其它任务，不占用线程等待
+			if fitsMemory(task.Memory) {
+				best = i
 			}
 		}
+		if best >= 0 {
+			task := p.queue[best]
+			if !tryReserveMemory(task.Memory) {
+				// 检查后内存被其它程序占用，重新选择
+				continue
+			}
+			p.queue = append(p.queue[:best], p.queue[best+1:]...)
+			p.running[task.Kind]++
+			return task, true
+		}
+		if p.closed && len(p.queue) == 0 {
+			return Task{}, false
+		}
+		p.cond.Wait()
 	}
 }
 
-func (p *WorkerPool) AddTask(task func() error) {
-	p.taskChan <- task
+// Submit 将任务加入队列，队列已满时返回 ErrQueueFull，任务需要的内存超过预算时返回错误，不会阻塞
+func (p *WorkerPool) Submit(task Task) error {
+	if err := CheckMemory(task.Memory); err != nil {
+		return err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return errors.New("worker pool is closed")
+	}
+	if len(p.queue) >= p.queueLength {
+		return ErrQueueFull
+	}
+	p.queue = append(p.queue, task)
+	p.cond.Broadcast()
+	return nil
 }
 
 func (p *WorkerPool) Close() {
-	p.once.Do(func() {
-		close(p.taskChan)
-		p.wg.Wait()
-	})
+	p.mu.Lock()
+	p.closed = true
+	p.mu.Unlock()
+	p.cond.Broadcast()
+	p.wg.Wait()
+}
+
+// InitWorkers 按配置重新创建线程池，需要在提交任务之前调用
+func InitWorkers(numWorkers, queueLength int, limits map[string]int) {
+	threadPool.Close()
+	threadPool = getWorkerPool(max(numWorkers, 1), max(queueLength, 1), limits)
 }
 
 func CloseWorkers() {
 	threadPool.Close()
 }
 
-func Execute(f func() error) {
-	threadPool.AddTask(f)
+// Submit 将任务加入线程池的队列，队列已满时返回 ErrQueueFull
+func Submit(task Task) error {
+	return threadPool.Submit(task)
+}
+
+// RunTask 在线程池中执行任务并等待其完成，排队期间 ctx 被取消时不再执行并返回 ctx.Err()
+func RunTask(ctx context.Context, task Task) error {
+	done := make(chan error, 1)
+	run := task.Run
+	task.Run = func() error {
+		if ctx.Err() != nil {
+			done <- ctx.Err()
+			return nil
+		}
+		err := run()
+		done <- err
+		return err
+	}
+	if err := Submit(task); err != nil {
+		return err
+	}
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
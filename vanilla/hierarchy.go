@@ -0,0 +1,70 @@
+package vanilla
+
+import (
+	"context"
+	"log/slog"
+	"pluto/classfile"
+	"pluto/mapping/java"
+	"sync"
+)
+
+var (
+	hierarchies     = map[string]*java.Hierarchy{}
+	hierarchiesLock sync.Mutex
+	// 同一版本同时只加载一次，不同版本互不等待
+	hierarchyLocks     = map[string]*sync.Mutex{}
+	hierarchyLocksLock sync.Mutex
+)
+
+// CachedHierarchy 返回已加载的继承关系，未加载时不会下载
+func CachedHierarchy(mcVersion string) (*java.Hierarchy, bool) {
+	hierarchiesLock.Lock()
+	defer hierarchiesLock.Unlock()
+	hierarchy, ok := hierarchies[mcVersion]
+	return hierarchy, ok
+}
+
+// LoadHierarchy 从原版 jar 的类文件中读取继承关系，结果按版本缓存，需要下载原版 jar，应在任务中调用
+func LoadHierarchy(ctx context.Context, mcVersion string) (*java.Hierarchy, error) {
+	if hierarchy, ok := CachedHierarchy(mcVersion); ok {
+		return hierarchy, nil
+	}
+	lock := getHierarchyLock(mcVersion)
+	lock.Lock()
+	defer lock.Unlock()
+	// 等待期间可能已经加载完成
+	if hierarchy, ok := CachedHierarchy(mcVersion); ok {
+		return hierarchy, nil
+	}
+	path, err := GetMcJarPathContext(ctx, mcVersion)
+	if err != nil {
+		return nil, err
+	}
+	slog.Info("Building class hierarchy for " + mcVersion)
+	hierarchy := java.NewHierarchy()
+	err = classfile.WalkJar(path, func(cf *classfile.ClassFile) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		hierarchy.Add(cf.ThisClass, cf.SuperClass, cf.Interfaces, cf.AccessFlags&classfile.AccInterface != 0)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	hierarchiesLock.Lock()
+	hierarchies[mcVersion] = hierarchy
+	hierarchiesLock.Unlock()
+	return hierarchy, nil
+}
+
+func getHierarchyLock(mcVersion string) *sync.Mutex {
+	hierarchyLocksLock.Lock()
+	defer hierarchyLocksLock.Unlock()
+	lock, ok := hierarchyLocks[mcVersion]
+	if !ok {
+		lock = &sync.Mutex{}
+		hierarchyLocks[mcVersion] = lock
+	}
+	return lock
+}
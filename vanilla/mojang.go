@@ -1,8 +1,10 @@
 package vanilla
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"log/slog"
 	"os"
 	"pluto/global"
@@ -36,6 +38,20 @@ type PistonData struct {
 
 var cache = map[string]Downloads{}
 
+var (
+	// ErrUnknownVersion 启动器清单中没有该版本，属于永久错误
+	ErrUnknownVersion = errors.New("Cannot find mc version")
+	ErrInvalidVersion = errors.New("invalid mc version")
+)
+
+// CheckVersion 版本号会拼接进缓存路径，拒绝空字符串和包含路径分隔符或 .. 的版本号
+func CheckVersion(mcVersion string) error {
+	if mcVersion == "" || strings.ContainsAny(mcVersion, "/\\:\x00") || strings.Contains(mcVersion, "..") {
+		return ErrInvalidVersion
+	}
+	return nil
+}
+
 func GetOrDownload(mcVersion string) (Downloads, error) {
 	if downloads, ok := cache[mcVersion]; ok {
 		return downloads, nil
@@ -58,7 +74,7 @@ func GetOrDownload(mcVersion string) (Downloads, error) {
 		}
 	}
 	if url == "" {
-		return Downloads{}, errors.New("Cannot find mc version " + mcVersion)
+		return Downloads{}, fmt.Errorf("%w %s", ErrUnknownVersion, mcVersion)
 	}
 	//request piston data
 	data, err = network.Get(replaceUrl(url))
@@ -77,6 +93,14 @@ func GetOrDownload(mcVersion string) (Downloads, error) {
 }
 
 func GetMcJarPath(mcVersion string) (string, error) {
+	return GetMcJarPathContext(context.Background(), mcVersion)
+}
+
+// GetMcJarPathContext 与 GetMcJarPath 相同，下载进度通过 ctx 报告
+func GetMcJarPathContext(ctx context.Context, mcVersion string) (string, error) {
+	if err := CheckVersion(mcVersion); err != nil {
+		return "", err
+	}
 	path := global.GetMinecraftPath(mcVersion)
 	if _, err := os.Stat(path); !os.IsNotExist(err) {
 		return path, nil
@@ -86,7 +110,7 @@ func GetMcJarPath(mcVersion string) (string, error) {
 		slog.Error("Unable to download " + mcVersion + " meta : " + err.Error())
 		return "", err
 	}
-	err = network.File(downloads.Client.Url, path)
+	err = network.FileContext(ctx, downloads.Client.Url, path)
 	if err != nil {
 		slog.Error("Unable to download " + mcVersion + " file : " + err.Error())
 		return "", err
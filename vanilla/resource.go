@@ -0,0 +1,161 @@
+package vanilla
+
+import (
+	"archive/zip"
+	"errors"
+	"io"
+	"mime"
+	"path"
+	"sort"
+	"strings"
+)
+
+type ResourceEntry struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+	Type string `json:"type"` // directory 或 file
+	Size int64  `json:"size,omitempty"`
+}
+
+var (
+	ErrInvalidResourcePath = errors.New("invalid resource path")
+	ErrResourceNotFound    = errors.New("resource not found")
+)
+
+// 部分游戏资源的扩展名在系统 mime 表中不存在或不准确
+var resourceContentTypes = map[string]string{
+	".json":       "application/json; charset=utf-8",
+	".mcmeta":     "application/json; charset=utf-8",
+	".lang":       "text/plain; charset=utf-8",
+	".txt":        "text/plain; charset=utf-8",
+	".properties": "text/plain; charset=utf-8",
+	".fsh":        "text/plain; charset=utf-8",
+	".vsh":        "text/plain; charset=utf-8",
+	".glsl":       "text/plain; charset=utf-8",
+	".png":        "image/png",
+	".ogg":        "audio/ogg",
+	".nbt":        "application/octet-stream",
+}
+
+// 资源是 jar 中除类文件和签名外的所有文件
+func isResourceEntry(name string) bool {
+	return !strings.HasSuffix(name, ".class") && !strings.HasPrefix(name, "META-INF/") && !strings.HasSuffix(name, "/")
+}
+
+func cleanResourcePath(p string) (string, error) {
+	p = strings.Trim(strings.ReplaceAll(p, "\\", "/"), "/")
+	if p == "" {
+		return "", nil
+	}
+	cleaned := path.Clean(p)
+	if cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return "", ErrInvalidResourcePath
+	}
+	return cleaned, nil
+}
+
+// ListResources 列出原版 jar 中某个目录下的资源文件和子目录，dir 为空时列出根目录
+func ListResources(mcVersion, dir string) ([]ResourceEntry, error) {
+	dir, err := cleanResourcePath(dir)
+	if err != nil {
+		return nil, err
+	}
+	jarPath, err := GetMcJarPath(mcVersion)
+	if err != nil {
+		return nil, err
+	}
+	jar, err := zip.OpenReader(jarPath)
+	if err != nil {
+		return nil, err
+	}
+	defer jar.Close()
+	prefix := dir
+	if prefix != "" {
+		prefix += "/"
+	}
+	// jar 中不一定有目录项，目录从文件路径推出
+	directories := make(map[string]struct{})
+	var result []ResourceEntry
+	for _, file := range jar.File {
+		if !isResourceEntry(file.Name) || !strings.HasPrefix(file.Name, prefix) {
+			continue
+		}
+		rest := file.Name[len(prefix):]
+		if name, _, nested := strings.Cut(rest, "/"); nested {
+			if _, ok := directories[name]; !ok {
+				directories[name] = struct{}{}
+				result = append(result, ResourceEntry{Name: name, Path: prefix + name, Type: "directory"})
+			}
+			continue
+		}
+		result = append(result, ResourceEntry{Name: rest, Path: file.Name, Type: "file", Size: int64(file.UncompressedSize64)})
+	}
+	if len(result) == 0 && dir != "" {
+		return nil, ErrResourceNotFound
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		if (result[i].Type == "directory") != (result[j].Type == "directory") {
+			return result[i].Type == "directory"
+		}
+		return result[i].Name < result[j].Name
+	})
+	return result, nil
+}
+
+// OpenResource 打开原版 jar 中的资源文件，返回内容、大小和 Content-Type，调用方负责关闭
+func OpenResource(mcVersion, p string) (io.ReadCloser, int64, string, error) {
+	p, err := cleanResourcePath(p)
+	if err != nil {
+		return nil, 0, "", err
+	}
+	if p == "" || !isResourceEntry(p) {
+		return nil, 0, "", ErrInvalidResourcePath
+	}
+	jarPath, err := GetMcJarPath(mcVersion)
+	if err != nil {
+		return nil, 0, "", err
+	}
+	jar, err := zip.OpenReader(jarPath)
+	if err != nil {
+		return nil, 0, "", err
+	}
+	for _, file := range jar.File {
+		if file.Name != p {
+			continue
+		}
+		rc, err := file.Open()
+		if err != nil {
+			jar.Close()
+			return nil, 0, "", err
+		}
+		return &resourceReader{ReadCloser: rc, jar: jar}, int64(file.UncompressedSize64), ResourceContentType(p), nil
+	}
+	jar.Close()
+	return nil, 0, "", ErrResourceNotFound
+}
+
+// ResourceContentType 根据扩展名判断资源的 Content-Type
+func ResourceContentType(p string) string {
+	ext := strings.ToLower(path.Ext(p))
+	if contentType, ok := resourceContentTypes[ext]; ok {
+		return contentType
+	}
+	if contentType := mime.TypeByExtension(ext); contentType != "" {
+		return contentType
+	}
+	return "application/octet-stream"
+}
+
+// 关闭资源时一并关闭 jar
+type resourceReader struct {
+	io.ReadCloser
+	jar *zip.ReadCloser
+}
+
+func (r *resourceReader) Close() error {
+	err := r.ReadCloser.Close()
+	if err := r.jar.Close(); err != nil {
+		return err
+	}
+	return err
+}
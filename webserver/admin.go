@@ -0,0 +1,24 @@
+package webserver
+
+import (
+	"crypto/subtle"
+	"github.com/gin-gonic/gin"
+	"log/slog"
+	"net/http"
+	"pluto/global"
+	"strings"
+)
+
+// AdminMiddleware 只允许携带 Authorization: Bearer <adminToken> 的请求，未配置 adminToken 时禁止所有请求
+func AdminMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if global.Config.AdminToken == "" || !ok || subtle.ConstantTimeCompare([]byte(token), []byte(global.Config.AdminToken)) != 1 {
+			c.String(http.StatusForbidden, "Forbidden")
+			slog.Warn("Unauthorized admin request from " + c.ClientIP())
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,75 @@
+package webserver
+
+import (
+	"bytes"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"os"
+	"pluto/classfile"
+	"pluto/mapping"
+	"pluto/util"
+	"pluto/vanilla"
+	"time"
+)
+
+func initBytecodeApi(g *gin.Engine) {
+	g.GET("/api/bytecode", RateLimiterMiddleware(2*time.Second, 5), func(c *gin.Context) {
+		mcVersion, mappingType, class := c.Query("version"), c.Query("type"), c.Query("class")
+		if mcVersion == "" || mappingType == "" || class == "" {
+			c.String(http.StatusBadRequest, "Missing query parameter(s)")
+			return
+		}
+		if err := vanilla.CheckVersion(mcVersion); err != nil {
+			c.String(http.StatusBadRequest, err.Error())
+			return
+		}
+		priority, ok := getPriority(c)
+		if !ok {
+			return
+		}
+		var cf *classfile.ClassFile
+		var err error
+		switch {
+		case mappingType == "notch":
+			var jarPath string
+			if jarPath, err = vanilla.GetMcJarPathContext(c.Request.Context(), mcVersion); err == nil {
+				cf, err = classfile.ReadClass(jarPath, class)
+			}
+		case util.Contains(mapping.GetMappingTypes(), mappingType):
+			cf, err = mapping.ReadRemappedClass(mcVersion, mappingType, class)
+		default:
+			c.String(http.StatusBadRequest, "Unknown mapping type")
+			return
+		}
+		if errors.Is(err, classfile.ErrClassNotFound) || errors.Is(err, vanilla.ErrUnknownVersion) {
+			c.String(http.StatusNotFound, err.Error())
+			return
+		}
+		if mappingType != "notch" && (errors.Is(err, os.ErrNotExist) || errors.Is(err, mapping.ErrRemapping)) {
+			// 与 /api/jar/download 相同，在后台重映射，原版 jar 在请求中直接下载
+			j, err := mapping.SubmitRemap(mcVersion, mappingType, priority)
+			if err != nil {
+				writeSubmitError(c, err)
+				return
+			}
+			c.JSON(http.StatusAccepted, j.Info())
+			return
+		}
+		if err != nil {
+			c.String(http.StatusInternalServerError, err.Error())
+			return
+		}
+		var out bytes.Buffer
+		err = classfile.Disassemble(&out, cf, c.Query("member"))
+		if errors.Is(err, classfile.ErrMemberNotFound) {
+			c.String(http.StatusNotFound, err.Error())
+			return
+		}
+		if err != nil {
+			c.String(http.StatusInternalServerError, err.Error())
+			return
+		}
+		c.Data(http.StatusOK, "text/plain; charset=utf-8", out.Bytes())
+	})
+}
@@ -0,0 +1,74 @@
+package webserver
+
+import (
+	"github.com/gin-gonic/gin"
+	"log/slog"
+	"net/http"
+	"pluto/mapping"
+	"pluto/mapping/java"
+	"time"
+)
+
+func initHierarchyApi(g *gin.Engine) {
+	g.GET("/api/hierarchy", RateLimiterMiddleware(2*time.Second, 5), func(c *gin.Context) {
+		mcVersion, mappingType, class := c.Query("version"), c.Query("type"), c.Query("class")
+		if mcVersion == "" || mappingType == "" || class == "" {
+			c.String(http.StatusBadRequest, "Missing query parameter(s)")
+			return
+		}
+		hierarchy, ok := getHierarchy(c, mcVersion, true)
+		if !ok {
+			return
+		}
+		if mappingType == "notch" {
+			result, ok := hierarchy.TypeHierarchy(class)
+			if !ok {
+				c.String(http.StatusNotFound, "Cannot find class "+class)
+				return
+			}
+			c.JSON(http.StatusOK, result)
+			return
+		}
+		mappings, err := mapping.LoadMapping(mcVersion, mappingType)
+		if err != nil {
+			c.String(http.StatusInternalServerError, err.Error())
+			return
+		}
+		notchClass, ok := mappings.FindClass(class)
+		if !ok {
+			c.String(http.StatusNotFound, "Cannot find class "+class)
+			return
+		}
+		result, ok := hierarchy.TypeHierarchy(notchClass.Class)
+		if !ok {
+			c.String(http.StatusNotFound, "Cannot find class "+class)
+			return
+		}
+		result.Rename(mappings.ClassName)
+		c.JSON(http.StatusOK, result)
+	})
+}
+
+// 返回已加载的继承关系，未加载时在后台加载原版 jar。required 为 true 时返回 202 和加载任务，
+// 否则不使用继承关系继续处理请求，返回 false 时已写入响应
+func getHierarchy(c *gin.Context, mcVersion string, required bool) (*java.Hierarchy, bool) {
+	priority, ok := getPriority(c)
+	if !ok {
+		return nil, false
+	}
+	hierarchy, j, err := mapping.GetHierarchy(mcVersion, priority)
+	switch {
+	case hierarchy != nil:
+		return hierarchy, true
+	case !required:
+		if err != nil {
+			slog.Warn("Failed to load class hierarchy: " + err.Error())
+		}
+		return nil, true
+	case err != nil:
+		writeSubmitError(c, err)
+		return nil, false
+	}
+	c.JSON(http.StatusAccepted, j.Info())
+	return nil, false
+}
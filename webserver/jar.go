@@ -0,0 +1,53 @@
+package webserver
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"os"
+	"pluto/mapping"
+	"pluto/util"
+	"pluto/vanilla"
+	"time"
+)
+
+func initJarApi(g *gin.Engine) {
+	g.GET("/api/jar/download", RateLimiterMiddleware(10*time.Second, 2), func(c *gin.Context) {
+		mcVersion, mappingType := c.Query("version"), c.Query("type")
+		if mcVersion == "" || mappingType == "" {
+			c.String(http.StatusBadRequest, "Missing query parameter(s)")
+			return
+		}
+		if !util.Contains(mapping.GetMappingTypes(), mappingType) {
+			c.String(http.StatusBadRequest, "Unknown mapping type")
+			return
+		}
+		if err := vanilla.CheckVersion(mcVersion); err != nil {
+			c.String(http.StatusBadRequest, err.Error())
+			return
+		}
+		priority, ok := getPriority(c)
+		if !ok {
+			return
+		}
+		path, checksum, release, err := mapping.OpenRemappedJar(mcVersion, mappingType)
+		if errors.Is(err, os.ErrNotExist) || errors.Is(err, mapping.ErrRemapping) {
+			// 正在重映射时返回同一个任务
+			j, err := mapping.SubmitRemap(mcVersion, mappingType, priority)
+			if err != nil {
+				writeSubmitError(c, err)
+				return
+			}
+			c.JSON(http.StatusAccepted, j.Info())
+			return
+		}
+		if err != nil {
+			c.String(http.StatusInternalServerError, err.Error())
+			return
+		}
+		defer release()
+		c.Header("X-Checksum-Sha256", checksum)
+		c.Header("ETag", "\""+checksum+"\"")
+		c.FileAttachment(path, mappingType+"-"+mcVersion+".jar")
+	})
+}
@@ -0,0 +1,107 @@
+package webserver
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"io"
+	"net/http"
+	"os"
+	"pluto/job"
+	"pluto/util"
+	"time"
+)
+
+func initJobApi(g *gin.Engine) {
+	g.GET("/api/jobs", RateLimiterMiddleware(100*time.Millisecond, 20), func(c *gin.Context) {
+		state, kind, mcVersion := c.Query("state"), c.Query("kind"), c.Query("version")
+		c.JSON(http.StatusOK, job.List(func(info job.Info) bool {
+			return (state == "" || string(info.State) == state) &&
+				(kind == "" || info.Kind == kind) &&
+				(mcVersion == "" || info.Version == mcVersion)
+		}))
+	})
+	g.GET("/api/jobs/:id", RateLimiterMiddleware(100*time.Millisecond, 20), func(c *gin.Context) {
+		j, ok := job.Get(c.Param("id"))
+		if !ok {
+			c.String(http.StatusNotFound, "Cannot find job "+c.Param("id"))
+			return
+		}
+		c.JSON(http.StatusOK, j.Info())
+	})
+	g.DELETE("/api/jobs/:id", RateLimiterMiddleware(2*time.Second, 5), AdminMiddleware(), func(c *gin.Context) {
+		j, err := job.Cancel(c.Param("id"))
+		switch {
+		case errors.Is(err, job.ErrNotFound):
+			c.String(http.StatusNotFound, "Cannot find job "+c.Param("id"))
+		case errors.Is(err, job.ErrFinished):
+			c.JSON(http.StatusConflict, j.Info())
+		default:
+			c.JSON(http.StatusOK, j.Info())
+		}
+	})
+	g.GET("/api/jobs/:id/log", RateLimiterMiddleware(2*time.Second, 5), func(c *gin.Context) {
+		j, ok := job.Get(c.Param("id"))
+		if !ok {
+			c.String(http.StatusNotFound, "Cannot find job "+c.Param("id"))
+			return
+		}
+		path := job.LogPath(j.ID())
+		if _, err := os.Stat(path); err != nil {
+			c.String(http.StatusNotFound, "The job has no log yet")
+			return
+		}
+		c.Header("Content-Type", "text/plain; charset=utf-8")
+		c.File(path)
+	})
+	g.GET("/api/jobs/:id/events", RateLimiterMiddleware(2*time.Second, 5), func(c *gin.Context) {
+		j, ok := job.Get(c.Param("id"))
+		if !ok {
+			c.String(http.StatusNotFound, "Cannot find job "+c.Param("id"))
+			return
+		}
+		events, cancel := j.Subscribe()
+		defer cancel()
+		// 防止代理因长时间没有数据断开连接
+		heartbeat := time.NewTicker(15 * time.Second)
+		defer heartbeat.Stop()
+		finished := false
+		c.Stream(func(w io.Writer) bool {
+			select {
+			case event, ok := <-events:
+				if !ok {
+					// 缓冲区满时结束事件可能被丢弃
+					if !finished {
+						c.SSEvent(string(job.EventState), j.Info())
+					}
+					return false
+				}
+				finished = event.Job.Finished()
+				c.SSEvent(string(event.Type), event.Job)
+				return true
+			case <-heartbeat.C:
+				c.SSEvent("ping", "")
+				return true
+			case <-c.Request.Context().Done():
+				return false
+			}
+		})
+	})
+}
+
+// 读取 priority 参数，high 留给有请求在等待的单类反编译，因此最高为 normal
+func getPriority(c *gin.Context) (util.Priority, bool) {
+	priority, err := util.ParsePriority(c.Query("priority"))
+	if err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return priority, false
+	}
+	return min(priority, util.PriorityNormal), true
+}
+
+func writeSubmitError(c *gin.Context, err error) {
+	if errors.Is(err, util.ErrQueueFull) {
+		c.String(http.StatusServiceUnavailable, "Queue is full, please try again later")
+		return
+	}
+	c.String(http.StatusBadRequest, err.Error())
+}
@@ -105,10 +105,10 @@ func (ls *Limiters) Stop() {
 	})
 }
 
-// RateLimiterMiddleware Gin middleware for rate limiting, with a dynamic key function
+// RateLimiterMiddleware Gin middleware for rate limiting, each route has its own bucket per client IP
 func RateLimiterMiddleware(interval time.Duration, max int) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		identifier := c.ClientIP()
+		identifier := c.FullPath() + "|" + c.ClientIP()
 		limiter := NewLimiter(rate.Every(interval), max, identifier, time.Minute*5, time.Minute*10)
 		if !limiter.Allow() {
 			c.String(http.StatusTooManyRequests, "Too many requests, please try again later.")
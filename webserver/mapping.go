@@ -4,12 +4,13 @@ import (
 	"github.com/gin-gonic/gin"
 	"net/http"
 	"pluto/mapping"
+	"pluto/mapping/java"
 	"time"
 )
 
 func initMappingApis(g *gin.Engine) {
 	g.GET("/api/mapping/search", RateLimiterMiddleware(2*time.Second, 5), func(c *gin.Context) {
-		mcVersion, mappingType, keyword, translate := c.Query("version"), c.Query("type"), c.Query("keyword"), c.Query("translate")
+		mcVersion, mappingType, keyword, translate, class := c.Query("version"), c.Query("type"), c.Query("keyword"), c.Query("translate"), c.Query("class")
 		if mcVersion == "" || mappingType == "" || keyword == "" {
 			c.String(http.StatusBadRequest, "Missing query parameter(s)")
 			return
@@ -23,15 +24,108 @@ func initMappingApis(g *gin.Engine) {
 			c.String(http.StatusInternalServerError, err.Error())
 			return
 		}
-		results := mappings.Search(keyword, 20)
+		// 限定类时需要继承关系，只翻译时在加载完成前不解析继承的成员
+		var hierarchy *java.Hierarchy
+		if class != "" || translate != "" {
+			var ok bool
+			if hierarchy, ok = getHierarchy(c, mcVersion, class != ""); !ok {
+				return
+			}
+		}
+		var results []java.InfoForNetwork
+		if class != "" {
+			notchClass, ok := mappings.FindClass(class)
+			if !ok {
+				c.String(http.StatusNotFound, "Cannot find class "+class)
+				return
+			}
+			results = mappings.SearchInClass(keyword, notchClass, hierarchy, 20)
+		} else {
+			results = mappings.Search(keyword, 20)
+		}
+		if translate != "" {
+			mappings, err := mapping.LoadMapping(mcVersion, translate)
+			if err != nil {
+				c.String(http.StatusInternalServerError, err.Error())
+				return
+			}
+			mappings.AppendTranslate(&results, hierarchy)
+		}
+		c.JSON(http.StatusOK, results)
+	})
+	g.GET("/api/mapping/resolve", RateLimiterMiddleware(2*time.Second, 5), func(c *gin.Context) {
+		mcVersion, mappingType, class, name, translate := c.Query("version"), c.Query("type"), c.Query("class"), c.Query("name"), c.Query("translate")
+		if mcVersion == "" || mappingType == "" || class == "" || name == "" {
+			c.String(http.StatusBadRequest, "Missing query parameter(s)")
+			return
+		}
+		mappings, err := mapping.LoadMapping(mcVersion, mappingType)
+		if err != nil {
+			c.String(http.StatusInternalServerError, err.Error())
+			return
+		}
+		notchClass, ok := mappings.FindClass(class)
+		if !ok {
+			c.String(http.StatusNotFound, "Cannot find class "+class)
+			return
+		}
+		hierarchy, ok := getHierarchy(c, mcVersion, true)
+		if !ok {
+			return
+		}
+		results := mappings.FindMember(notchClass, name, c.Query("descriptor"), hierarchy)
+		if len(results) == 0 {
+			c.String(http.StatusNotFound, "Cannot find member "+name+" in "+class+" or its supertypes")
+			return
+		}
 		if translate != "" {
 			mappings, err := mapping.LoadMapping(mcVersion, translate)
 			if err != nil {
 				c.String(http.StatusInternalServerError, err.Error())
 				return
 			}
-			mappings.AppendTranslate(&results)
+			mappings.AppendTranslate(&results, hierarchy)
 		}
 		c.JSON(http.StatusOK, results)
 	})
+	g.GET("/api/mapping/suggest", RateLimiterMiddleware(100*time.Millisecond, 20), func(c *gin.Context) {
+		mcVersion, mappingType, prefix := c.Query("version"), c.Query("type"), c.Query("prefix")
+		if mcVersion == "" || mappingType == "" || prefix == "" {
+			c.String(http.StatusBadRequest, "Missing query parameter(s)")
+			return
+		}
+		mappings, err := mapping.LoadMapping(mcVersion, mappingType)
+		if err != nil {
+			c.String(http.StatusInternalServerError, err.Error())
+			return
+		}
+		c.JSON(http.StatusOK, mappings.Suggest(prefix, 10))
+	})
+	g.GET("/api/mapping/changelog", RateLimiterMiddleware(10*time.Second, 2), func(c *gin.Context) {
+		mappingType, from, to, format := c.Query("type"), c.Query("from"), c.Query("to"), c.DefaultQuery("format", "json")
+		if mappingType == "" || from == "" || to == "" {
+			c.String(http.StatusBadRequest, "Missing query parameter(s)")
+			return
+		}
+		if format != "json" && format != "markdown" {
+			c.String(http.StatusBadRequest, "Format must be json or markdown")
+			return
+		}
+		fromMappings, err := mapping.LoadMapping(from, mappingType)
+		if err != nil {
+			c.String(http.StatusInternalServerError, err.Error())
+			return
+		}
+		toMappings, err := mapping.LoadMapping(to, mappingType)
+		if err != nil {
+			c.String(http.StatusInternalServerError, err.Error())
+			return
+		}
+		changelog := java.Diff(from, to, fromMappings, toMappings)
+		if format == "markdown" {
+			c.Data(http.StatusOK, "text/markdown; charset=utf-8", []byte(changelog.Markdown(mappingType)))
+			return
+		}
+		c.JSON(http.StatusOK, changelog)
+	})
 }
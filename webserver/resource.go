@@ -0,0 +1,56 @@
+package webserver
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"log/slog"
+	"net/http"
+	"pluto/vanilla"
+	"time"
+)
+
+func initResourceApi(g *gin.Engine) {
+	g.GET("/api/resource/list", RateLimiterMiddleware(2*time.Second, 5), func(c *gin.Context) {
+		mcVersion := c.Query("version")
+		if mcVersion == "" {
+			c.String(http.StatusBadRequest, "Missing query parameter(s)")
+			return
+		}
+		entries, err := vanilla.ListResources(mcVersion, c.Query("path"))
+		if !writeResourceError(c, err) {
+			c.JSON(http.StatusOK, entries)
+		}
+	})
+	g.GET("/api/resource/get", RateLimiterMiddleware(100*time.Millisecond, 20), func(c *gin.Context) {
+		mcVersion, path := c.Query("version"), c.Query("path")
+		if mcVersion == "" || path == "" {
+			c.String(http.StatusBadRequest, "Missing query parameter(s)")
+			return
+		}
+		reader, size, contentType, err := vanilla.OpenResource(mcVersion, path)
+		if writeResourceError(c, err) {
+			return
+		}
+		defer func() {
+			if err := reader.Close(); err != nil {
+				slog.Error("Failed to close resource: " + err.Error())
+			}
+		}()
+		c.DataFromReader(http.StatusOK, size, contentType, reader, nil)
+	})
+}
+
+// 写入资源接口的错误响应，没有错误时返回 false
+func writeResourceError(c *gin.Context, err error) bool {
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, vanilla.ErrInvalidResourcePath), errors.Is(err, vanilla.ErrInvalidVersion):
+		c.String(http.StatusBadRequest, err.Error())
+	case errors.Is(err, vanilla.ErrResourceNotFound), errors.Is(err, vanilla.ErrUnknownVersion):
+		c.String(http.StatusNotFound, err.Error())
+	default:
+		c.String(http.StatusInternalServerError, err.Error())
+	}
+	return true
+}
@@ -21,6 +21,12 @@ func Launch() error {
 	})
 	initMappingApis(g)
 	initSourceApi(g)
+	initResourceApi(g)
+	initJarApi(g)
+	initBytecodeApi(g)
+	initHierarchyApi(g)
+	initTranslateApi(g)
+	initJobApi(g)
 	err := g.Run(":" + strconv.Itoa(global.Config.Port))
 	return err
 }
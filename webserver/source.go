@@ -1,13 +1,19 @@
 package webserver
 
 import (
+	"errors"
 	"github.com/gin-gonic/gin"
+	"log/slog"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
 	"pluto/global"
 	"pluto/mapping"
+	"pluto/source"
 	"pluto/util"
+	"pluto/vanilla"
+	"strconv"
 	"time"
 )
 
@@ -18,41 +24,298 @@ func initSourceApi(g *gin.Engine) {
 			c.String(http.StatusBadRequest, "Missing query parameter(s)")
 			return
 		}
-		if mapping.IsAvailable(mcVersion, mappingType) {
+		decompiler, err := mapping.GetDecompiler(c.Query("decompiler"))
+		if err != nil {
+			c.String(http.StatusBadRequest, err.Error())
+			return
+		}
+		priority, ok := getPriority(c)
+		if !ok {
+			return
+		}
+		if mapping.IsAvailable(mcVersion, mappingType, decompiler.GetName()) {
 			c.String(http.StatusOK, "Decompiled")
 			return
 		}
-		if mapping.IsPending(mcVersion, mappingType) {
-			c.String(http.StatusForbidden, "This task is pending")
+		j, err := mapping.SubmitDecompile(mcVersion, mappingType, decompiler.GetName(), priority)
+		if err != nil {
+			writeSubmitError(c, err)
 			return
 		}
-		util.Execute(func() error {
-			_, err := mapping.GenerateSource(mcVersion, mappingType)
-			return err
-		})
-		c.String(http.StatusAccepted, "Started decompiling, please wait")
+		c.JSON(http.StatusAccepted, j.Info())
 	})
 	g.GET("/api/source/get", RateLimiterMiddleware(2*time.Second, 5), func(c *gin.Context) {
-		mcVersion, mappingType, class := c.Query("version"), c.Query("type"), c.Query("class")
-		if mcVersion == "" || mappingType == "" || class == "" {
+		class, member, lines := c.Query("class"), c.Query("member"), c.Query("lines")
+		if class == "" {
 			c.String(http.StatusBadRequest, "Missing query parameter(s)")
 			return
 		}
-		if !mapping.IsAvailable(mcVersion, mappingType) {
-			c.String(http.StatusPreconditionFailed, "Use /load before getting")
+		if member != "" && lines != "" {
+			c.String(http.StatusBadRequest, "Use either member or lines")
+			return
+		}
+		path, ok := getSourceFolderOrClass(c, class)
+		if !ok {
+			return
+		}
+		classPath, inner, err := source.LocateClass(path, class)
+		if errors.Is(err, source.ErrNotFound) {
+			c.String(http.StatusNotFound, "")
+			return
+		}
+		if err != nil {
+			c.String(http.StatusBadRequest, err.Error())
+			return
+		}
+		content, err := os.ReadFile(filepath.Join(path, filepath.FromSlash(classPath)+".java"))
+		if err != nil {
+			c.String(http.StatusInternalServerError, "Failed to read file")
+			return
+		}
+		c.Header("X-Source-File", classPath)
+		if lines == "" && member == "" && len(inner) == 0 {
+			c.Data(http.StatusOK, "text/plain; charset=utf-8", content)
+			return
+		}
+		var start, end int
+		if lines != "" {
+			start, end, err = source.ParseLineRange(lines)
+		} else {
+			start, end, err = source.FindRange(string(content), classPath, inner, member)
+		}
+		if errors.Is(err, source.ErrNotFound) {
+			c.String(http.StatusNotFound, err.Error())
+			return
+		}
+		if err != nil {
+			c.String(http.StatusBadRequest, err.Error())
+			return
+		}
+		count := source.CountLines(string(content))
+		if start > count {
+			c.String(http.StatusRequestedRangeNotSatisfiable, "The file has only "+strconv.Itoa(count)+" lines")
+			return
+		}
+		end = min(end, count)
+		c.Header("X-Source-Lines", strconv.Itoa(start)+"-"+strconv.Itoa(end))
+		c.String(http.StatusOK, source.NumberLines(string(content), start, end))
+	})
+	g.GET("/api/source/tree", RateLimiterMiddleware(2*time.Second, 5), func(c *gin.Context) {
+		path, ok := getSourceFolder(c)
+		if !ok {
+			return
+		}
+		entries, err := source.ListTree(path, c.Query("path"))
+		if errors.Is(err, source.ErrOutsideFolder) {
+			c.String(http.StatusBadRequest, err.Error())
+			return
+		}
+		if os.IsNotExist(err) {
+			c.String(http.StatusNotFound, "")
+			return
+		}
+		if err != nil {
+			c.String(http.StatusInternalServerError, err.Error())
+			return
+		}
+		c.JSON(http.StatusOK, entries)
+	})
+	g.GET("/api/source/download", RateLimiterMiddleware(10*time.Second, 2), func(c *gin.Context) {
+		pkg, format := c.Query("package"), c.DefaultQuery("format", "zip")
+		if format != "zip" && format != "jar" {
+			c.String(http.StatusBadRequest, "Format must be zip or jar")
+			return
+		}
+		path, ok := getSourceFolder(c)
+		if !ok {
 			return
 		}
-		path := global.GetSourceFolder(global.NamedImpl{Name: mappingType}, mcVersion)
-		targetPath := filepath.Join(path, class+".java")
-		if _, err := os.Stat(targetPath); os.IsNotExist(err) {
+		root, err := source.ResolvePath(path, source.PackageToPath(pkg))
+		if err != nil {
+			c.String(http.StatusBadRequest, err.Error())
+			return
+		}
+		if _, err := os.Stat(root); os.IsNotExist(err) {
 			c.String(http.StatusNotFound, "")
 			return
 		}
+		fileName := c.Query("type") + "-" + c.Query("version") + "-sources." + format
+		c.Header("Content-Disposition", "attachment; filename=\""+fileName+"\"")
+		c.Header("Content-Type", "application/zip")
+		c.Status(http.StatusOK)
+		if err := source.WriteArchive(c.Writer, path, pkg, format == "jar"); err != nil {
+			slog.Error("Failed to write source archive: " + err.Error())
+		}
+	})
+	g.GET("/api/source/view", RateLimiterMiddleware(2*time.Second, 5), func(c *gin.Context) {
+		mcVersion, mappingType, class := c.Query("version"), c.Query("type"), c.Query("class")
+		if class == "" {
+			c.String(http.StatusBadRequest, "Missing query parameter(s)")
+			return
+		}
+		path, ok := getSourceFolderOrClass(c, class)
+		if !ok {
+			return
+		}
+		targetPath, err := source.ResolvePath(path, class+".java")
+		if err != nil {
+			c.String(http.StatusBadRequest, err.Error())
+			return
+		}
 		content, err := os.ReadFile(targetPath)
+		if os.IsNotExist(err) {
+			c.String(http.StatusNotFound, "")
+			return
+		}
 		if err != nil {
 			c.String(http.StatusInternalServerError, "Failed to read file")
 			return
 		}
-		c.Data(http.StatusOK, "text/plain; charset=utf-8", content)
+		describer, err := mapping.NewDescriber(mcVersion, mappingType)
+		if err != nil {
+			slog.Warn("Rendering source without mapping names: " + err.Error())
+		}
+		c.Header("Content-Type", "text/html; charset=utf-8")
+		c.Status(http.StatusOK)
+		err = source.RenderHTML(c.Writer, path, class, content, source.ViewOptions{
+			Title: mappingType + " " + mcVersion + " - " + class,
+			Link: func(classPath string) string {
+				return "/api/source/view?version=" + url.QueryEscape(mcVersion) + "&type=" + url.QueryEscape(mappingType) + "&class=" + url.QueryEscape(classPath)
+			},
+			Describe: describer,
+		})
+		if err != nil {
+			slog.Error("Failed to render source: " + err.Error())
+		}
+	})
+	g.GET("/api/source/xref", RateLimiterMiddleware(2*time.Second, 5), func(c *gin.Context) {
+		mcVersion, mappingType, symbol := c.Query("version"), c.Query("type"), c.Query("symbol")
+		if symbol == "" {
+			c.String(http.StatusBadRequest, "Missing query parameter(s)")
+			return
+		}
+		if _, ok := getSourceFolder(c); !ok {
+			return
+		}
+		index, err := source.GetXref(global.GetRemappedPath(global.NamedImpl{Name: mappingType}, mcVersion))
+		if err != nil {
+			c.String(http.StatusInternalServerError, err.Error())
+			return
+		}
+		c.JSON(http.StatusOK, index.Query(symbol, 1000))
+	})
+	g.GET("/api/source/diff", RateLimiterMiddleware(10*time.Second, 2), func(c *gin.Context) {
+		mappingType, from, to := c.Query("type"), c.Query("from"), c.Query("to")
+		decompiler := c.Query("decompiler")
+		fromPath, ok := getSourceFolderOf(c, from, mappingType, c.DefaultQuery("fromDecompiler", decompiler))
+		if !ok {
+			return
+		}
+		toPath, ok := getSourceFolderOf(c, to, mappingType, c.DefaultQuery("toDecompiler", decompiler))
+		if !ok {
+			return
+		}
+		result, err := source.DiffTrees(fromPath, toPath, c.Query("class"), 200)
+		if err != nil {
+			c.String(http.StatusInternalServerError, err.Error())
+			return
+		}
+		c.JSON(http.StatusOK, result)
+	})
+	g.GET("/api/source/search", RateLimiterMiddleware(2*time.Second, 5), func(c *gin.Context) {
+		query, mode := c.Query("q"), c.DefaultQuery("mode", "literal")
+		if query == "" {
+			c.String(http.StatusBadRequest, "Missing query parameter(s)")
+			return
+		}
+		if mode != "literal" && mode != "regex" {
+			c.String(http.StatusBadRequest, "Mode must be literal or regex")
+			return
+		}
+		path, ok := getSourceFolder(c)
+		if !ok {
+			return
+		}
+		hits, err := source.Search(path, query, mode == "regex", c.Query("ignoreCase") == "true", 100)
+		if err != nil {
+			c.String(http.StatusBadRequest, err.Error())
+			return
+		}
+		c.JSON(http.StatusOK, hits)
 	})
 }
+
+// 校验 version、type 和 decompiler 参数并返回已反编译的源码目录，失败时已写入响应
+func getSourceFolder(c *gin.Context) (string, bool) {
+	return getSourceFolderOf(c, c.Query("version"), c.Query("type"), c.Query("decompiler"))
+}
+
+func getSourceFolderOf(c *gin.Context, mcVersion, mappingType, decompilerName string) (string, bool) {
+	if mcVersion == "" || mappingType == "" {
+		c.String(http.StatusBadRequest, "Missing query parameter(s)")
+		return "", false
+	}
+	decompiler, err := mapping.GetDecompiler(decompilerName)
+	if err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return "", false
+	}
+	if !mapping.IsAvailable(mcVersion, mappingType, decompiler.GetName()) {
+		c.String(http.StatusPreconditionFailed, "Use /api/source/decompile for "+mcVersion+" before getting")
+		return "", false
+	}
+	return global.GetSourceFolder(global.NamedImpl{Name: mappingType}, mcVersion, decompiler.GetName()), true
+}
+
+// 完整源码不可用时只反编译请求的类，返回单独反编译结果的目录
+func getSourceFolderOrClass(c *gin.Context, class string) (string, bool) {
+	mcVersion, mappingType := c.Query("version"), c.Query("type")
+	if mcVersion == "" || mappingType == "" {
+		c.String(http.StatusBadRequest, "Missing query parameter(s)")
+		return "", false
+	}
+	if !util.Contains(mapping.GetMappingTypes(), mappingType) {
+		c.String(http.StatusBadRequest, "Unknown mapping type")
+		return "", false
+	}
+	decompiler, err := mapping.GetDecompiler(c.Query("decompiler"))
+	if err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return "", false
+	}
+	if mapping.IsAvailable(mcVersion, mappingType, decompiler.GetName()) {
+		return global.GetSourceFolder(global.NamedImpl{Name: mappingType}, mcVersion, decompiler.GetName()), true
+	}
+	priority, ok := getPriority(c)
+	if !ok {
+		return "", false
+	}
+	folder, err := mapping.DecompileClass(c.Request.Context(), mcVersion, mappingType, decompiler.GetName(), class)
+	if errors.Is(err, os.ErrNotExist) || errors.Is(err, mapping.ErrRemapping) {
+		// 先在后台重映射，jar 生成后才反编译单个类
+		j, err := mapping.SubmitRemap(mcVersion, mappingType, priority)
+		if err != nil {
+			writeSubmitError(c, err)
+			return "", false
+		}
+		c.JSON(http.StatusAccepted, j.Info())
+		return "", false
+	}
+	if errors.Is(err, vanilla.ErrInvalidVersion) {
+		c.String(http.StatusBadRequest, err.Error())
+		return "", false
+	}
+	if errors.Is(err, mapping.ErrClassNotFound) {
+		c.String(http.StatusNotFound, err.Error())
+		return "", false
+	}
+	if errors.Is(err, util.ErrQueueFull) {
+		c.String(http.StatusServiceUnavailable, "Queue is full, please try again later")
+		return "", false
+	}
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Failed to decompile class: "+err.Error())
+		return "", false
+	}
+	return folder, true
+}
@@ -0,0 +1,44 @@
+package webserver
+
+import (
+	"github.com/gin-gonic/gin"
+	"io"
+	"net/http"
+	"pluto/mapping"
+	"pluto/mapping/java"
+	"time"
+)
+
+// 代码片段的最大长度
+const maxSnippetSize = 1 << 20
+
+func initTranslateApi(g *gin.Engine) {
+	g.POST("/api/translate/source", RateLimiterMiddleware(2*time.Second, 5), func(c *gin.Context) {
+		mcVersion, from, to := c.Query("version"), c.Query("from"), c.Query("to")
+		if mcVersion == "" || from == "" || to == "" {
+			c.String(http.StatusBadRequest, "Missing query parameter(s)")
+			return
+		}
+		body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxSnippetSize))
+		if err != nil {
+			c.String(http.StatusRequestEntityTooLarge, "Source is too large")
+			return
+		}
+		fromMappings, err := mapping.LoadMapping(mcVersion, from)
+		if err != nil {
+			c.String(http.StatusInternalServerError, err.Error())
+			return
+		}
+		toMappings, err := mapping.LoadMapping(mcVersion, to)
+		if err != nil {
+			c.String(http.StatusInternalServerError, err.Error())
+			return
+		}
+		// 继承关系在后台加载，加载完成前只能在声明的类中查找成员
+		hierarchy, ok := getHierarchy(c, mcVersion, false)
+		if !ok {
+			return
+		}
+		c.String(http.StatusOK, java.TranslateSource(string(body), fromMappings, toMappings, hierarchy))
+	})
+}